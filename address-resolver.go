@@ -10,7 +10,12 @@ import (
 type AddressResolver struct {
 	object       dbus.BusObject
 	FoundChannel chan Address
-	closeCh      chan struct{}
+
+	// FailureChannel receives the error reported by the daemon when resolving failed.
+	FailureChannel chan error
+
+	closeCh chan struct{}
+	queue   *signalQueue
 }
 
 // AddressResolverNew creates a new AddressResolver
@@ -20,6 +25,8 @@ func AddressResolverNew(conn *dbus.Conn, path dbus.ObjectPath) (*AddressResolver
 	c.object = conn.Object("org.freedesktop.Avahi", path)
 	c.FoundChannel = make(chan Address)
 	c.closeCh = make(chan struct{})
+	c.queue = newSignalQueue()
+	c.FailureChannel = make(chan error, 1)
 
 	return c, nil
 }
@@ -35,11 +42,39 @@ func (c *AddressResolver) free() {
 	c.object.Call(c.interfaceForMember("Free"), 0)
 }
 
+// Dropped returns the number of signals discarded because the queue was full
+func (c *AddressResolver) Dropped() uint64 {
+	return c.queue.droppedCount()
+}
+
+func (c *AddressResolver) getSignalQueue() *signalQueue {
+	return c.queue
+}
+
 func (c *AddressResolver) getObjectPath() dbus.ObjectPath {
 	return c.object.Path()
 }
 
+func (c *AddressResolver) restore(conn *dbus.Conn, path dbus.ObjectPath) error {
+	c.object = conn.Object("org.freedesktop.Avahi", path)
+	return nil
+}
+
 func (c *AddressResolver) dispatchSignal(signal *dbus.Signal) error {
+	if signal.Name == c.interfaceForMember("Failure") {
+		var e string
+		err := dbus.Store(signal.Body, &e)
+		if err != nil {
+			return err
+		}
+
+		select {
+		case c.FailureChannel <- avahiErrorFromString(e):
+		default:
+		}
+		return nil
+	}
+
 	if signal.Name == c.interfaceForMember("Found") {
 		var address Address
 		err := dbus.Store(signal.Body, &address.Interface, &address.Protocol,
@@ -0,0 +1,224 @@
+package avahitest
+
+import (
+	"strings"
+
+	dbus "github.com/godbus/dbus/v5"
+	"github.com/holoplot/go-avahi"
+)
+
+const (
+	domainBrowser      = "DomainBrowser"
+	serviceTypeBrowser = "ServiceTypeBrowser"
+	serviceBrowser     = "ServiceBrowser"
+	recordBrowser      = "RecordBrowser"
+)
+
+// browser implements the DomainBrowser, ServiceTypeBrowser, ServiceBrowser and RecordBrowser interfaces
+type browser struct {
+	d       *Daemon
+	kind    string
+	path    dbus.ObjectPath
+	owner   string
+	started bool
+
+	iface       int32
+	protocol    int32
+	domain      string
+	btype       int32
+	serviceType string
+	name        string
+	class       uint16
+	recordType  uint16
+	flags       uint32
+
+	// types holds the service types reported by a ServiceTypeBrowser, with a reference count
+	types map[avahi.ServiceType]int
+}
+
+// newBrowser registers b. Browsers created through the Server interface start right away,
+// prepared ones on Start. The caller must not hold d.mutex.
+func (d *Daemon) newBrowser(sender dbus.Sender, b *browser, start bool) (dbus.ObjectPath, *dbus.Error) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	b.d = d
+	b.owner = string(sender)
+	b.path = d.newObjectPath(sender, b.kind)
+	b.types = make(map[avahi.ServiceType]int)
+
+	err := d.exportObject(b, b.path, b.interfaceName())
+	if err != nil {
+		return "", err
+	}
+
+	if start {
+		b.start()
+	}
+
+	return b.path, nil
+}
+
+func (b *browser) interfaceName() string {
+	return "org.freedesktop.Avahi." + b.kind
+}
+
+func (b *browser) Free() *dbus.Error {
+	b.d.mutex.Lock()
+	defer b.d.mutex.Unlock()
+
+	return b.d.freeObject(b.path, b.interfaceName())
+}
+
+func (b *browser) Start() *dbus.Error {
+	b.d.mutex.Lock()
+	defer b.d.mutex.Unlock()
+
+	if b.started {
+		return errBadState
+	}
+
+	b.start()
+
+	return nil
+}
+
+func (b *browser) free() {
+	b.started = false
+}
+
+func (b *browser) emit(member string, args ...interface{}) {
+	b.d.emit(b.owner, b.path, b.interfaceName(), member, args...)
+}
+
+// start reports everything already known, followed by CacheExhausted and AllForNow.
+// The caller must hold d.mutex.
+func (b *browser) start() {
+	b.started = true
+
+	switch b.kind {
+	case domainBrowser:
+		for _, domain := range b.d.domains {
+			if b.matchDomain(domain) {
+				b.emit("ItemNew", domain.Interface, domain.Protocol, domain.Domain, domain.Flags)
+			}
+		}
+
+	case serviceTypeBrowser, serviceBrowser:
+		for _, s := range b.d.services {
+			b.serviceAdded(s)
+		}
+
+	case recordBrowser:
+		for _, r := range b.d.records {
+			if b.matchRecord(r.Record) {
+				b.emitRecord("ItemNew", r.Record)
+			}
+		}
+	}
+
+	b.emit("CacheExhausted")
+	b.emit("AllForNow")
+}
+
+func (b *browser) matchDomain(domain avahi.Domain) bool {
+	return matchInterface(b.iface, domain.Interface) && matchProtocol(b.protocol, domain.Protocol)
+}
+
+func (b *browser) matchRecord(r avahi.Record) bool {
+	return matchInterface(b.iface, r.Interface) && matchProtocol(b.protocol, r.Protocol) &&
+		strings.EqualFold(strings.TrimSuffix(b.name, "."), r.Name) &&
+		b.class == r.Class && (b.recordType == avahi.DNSTypeANY || b.recordType == r.Type)
+}
+
+func (b *browser) matchService(s *service) bool {
+	if !matchInterface(b.iface, s.Interface) || !matchProtocol(b.protocol, s.Protocol) || !matchDomain(b.domain, s.Domain) {
+		return false
+	}
+
+	if b.kind == serviceTypeBrowser {
+		return true
+	}
+
+	if strings.EqualFold(b.serviceType, s.Type) {
+		return true
+	}
+
+	for _, subtype := range s.subtypes {
+		if strings.EqualFold(b.serviceType, subtype) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// serviceFlags returns the lookup result flags of s as seen by the owner of b
+func (b *browser) serviceFlags(s *service) uint32 {
+	if s.group == nil {
+		return avahi.LookupResultMulticast
+	}
+
+	flags := uint32(avahi.LookupResultLocal)
+	if s.group.owner == b.owner {
+		flags |= avahi.LookupResultOurOwn
+	}
+
+	return flags
+}
+
+// typeOf returns the type s is reported with, which is the subtype when browsing for one
+func (b *browser) typeOf(s *service) string {
+	if strings.EqualFold(b.serviceType, s.Type) {
+		return s.Type
+	}
+
+	return b.serviceType
+}
+
+func (b *browser) serviceTypeOf(s *service) avahi.ServiceType {
+	return avahi.ServiceType{Interface: s.Interface, Protocol: s.Protocol, Type: s.Type, Domain: s.Domain}
+}
+
+// serviceAdded reports s if it matches. The caller must hold d.mutex.
+func (b *browser) serviceAdded(s *service) {
+	if !b.matchService(s) {
+		return
+	}
+
+	switch b.kind {
+	case serviceBrowser:
+		b.emit("ItemNew", s.Interface, s.Protocol, s.Name, b.typeOf(s), s.Domain, b.serviceFlags(s))
+
+	case serviceTypeBrowser:
+		t := b.serviceTypeOf(s)
+		b.types[t]++
+		if b.types[t] == 1 {
+			b.emit("ItemNew", t.Interface, t.Protocol, t.Type, t.Domain, b.serviceFlags(s))
+		}
+	}
+}
+
+// serviceRemoved reports the removal of s if it matches. The caller must hold d.mutex.
+func (b *browser) serviceRemoved(s *service) {
+	if !b.matchService(s) {
+		return
+	}
+
+	switch b.kind {
+	case serviceBrowser:
+		b.emit("ItemRemove", s.Interface, s.Protocol, s.Name, b.typeOf(s), s.Domain, b.serviceFlags(s))
+
+	case serviceTypeBrowser:
+		t := b.serviceTypeOf(s)
+		b.types[t]--
+		if b.types[t] == 0 {
+			delete(b.types, t)
+			b.emit("ItemRemove", t.Interface, t.Protocol, t.Type, t.Domain, b.serviceFlags(s))
+		}
+	}
+}
+
+func (b *browser) emitRecord(member string, r avahi.Record) {
+	b.emit(member, r.Interface, r.Protocol, r.Name, r.Class, r.Type, r.Rdata, r.Flags)
+}
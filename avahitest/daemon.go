@@ -0,0 +1,634 @@
+// Package avahitest provides an in-process stand-in for avahi-daemon, exported
+// on a private D-Bus message bus. It implements the org.freedesktop.Avahi D-Bus
+// API closely enough for hermetic tests of code built on the avahi package.
+//
+// The fake network is scripted: services, host names, records and domains are
+// added and removed by the test, and every browser and resolver reacts to such
+// changes just like it would to packets on the wire. Entry groups committed by
+// clients publish their services on the same fake network.
+package avahitest
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	dbus "github.com/godbus/dbus/v5"
+	"github.com/godbus/dbus/v5/introspect"
+	"github.com/holoplot/go-avahi"
+)
+
+// ErrNoBusDaemon is returned by New if no dbus-daemon executable can be found
+var ErrNoBusDaemon = errors.New("dbus-daemon executable not found")
+
+const busConfig = `<!DOCTYPE busconfig PUBLIC "-//freedesktop//DTD D-Bus Bus Configuration 1.0//EN"
+ "http://www.freedesktop.org/standards/dbus/1.0/busconfig.dtd">
+<busconfig>
+  <type>session</type>
+  <listen>unix:path=%s</listen>
+  <auth>EXTERNAL</auth>
+  <policy context="default">
+    <allow send_destination="*" eavesdrop="true"/>
+    <allow eavesdrop="true"/>
+    <allow own="*"/>
+  </policy>
+</busconfig>
+`
+
+const introspectionServer = `<node>
+  <interface name="org.freedesktop.DBus.Introspectable"/>
+  <interface name="org.freedesktop.Avahi.Server"/>
+</node>`
+
+const introspectionServer2 = `<node>
+  <interface name="org.freedesktop.DBus.Introspectable"/>
+  <interface name="org.freedesktop.Avahi.Server"/>
+  <interface name="org.freedesktop.Avahi.Server2"/>
+</node>`
+
+// A Daemon is a fake avahi-daemon on a private bus
+type Daemon struct {
+	dir     string
+	address string
+	cmd     *exec.Cmd
+	conn    *dbus.Conn
+
+	mutex          sync.Mutex
+	resolveTimeout time.Duration
+	server2        bool
+	state          int32
+	hostName       string
+	domainName     string
+	interfaces     map[int32]string
+	cookie         uint32
+
+	clients map[string]*client
+	objects map[dbus.ObjectPath]object
+
+	services   []*service
+	hosts      []*host
+	records    []*record
+	domains    []avahi.Domain
+	collisions map[string]bool
+}
+
+type client struct {
+	id      int
+	counter int
+}
+
+// an object is anything a client created on the daemon
+type object interface {
+	free()
+}
+
+// a service is announced on the fake network, either scripted or published by an entry group
+type service struct {
+	avahi.Service
+	subtypes     []string
+	group        *entryGroup
+	unresolvable bool
+}
+
+type host struct {
+	avahi.HostName
+	group *entryGroup
+}
+
+type record struct {
+	avahi.Record
+	group *entryGroup
+}
+
+// New starts a private dbus-daemon and exports a fake Avahi server on it.
+// The fake host is called "fakehost" and has the network interfaces "lo" (1) and "eth0" (2).
+func New() (*Daemon, error) {
+	binary, err := exec.LookPath("dbus-daemon")
+	if err != nil {
+		return nil, ErrNoBusDaemon
+	}
+
+	d := &Daemon{
+		resolveTimeout: time.Second,
+		server2:        true,
+		state:          avahi.ServerRunning,
+		hostName:       "fakehost",
+		domainName:     "local",
+		interfaces:     map[int32]string{1: "lo", 2: "eth0"},
+		cookie:         0x4a1c6b2e,
+		clients:        make(map[string]*client),
+		objects:        make(map[dbus.ObjectPath]object),
+		collisions:     make(map[string]bool),
+	}
+
+	d.dir, err = os.MkdirTemp("", "avahitest")
+	if err != nil {
+		return nil, err
+	}
+
+	config := filepath.Join(d.dir, "bus.conf")
+	err = os.WriteFile(config, []byte(fmt.Sprintf(busConfig, filepath.Join(d.dir, "bus"))), 0600)
+	if err != nil {
+		d.Close()
+		return nil, err
+	}
+
+	d.cmd = exec.Command(binary, "--config-file="+config, "--nofork", "--print-address")
+	stdout, err := d.cmd.StdoutPipe()
+	if err != nil {
+		d.Close()
+		return nil, err
+	}
+
+	err = d.cmd.Start()
+	if err != nil {
+		d.cmd = nil
+		d.Close()
+		return nil, err
+	}
+
+	d.address, err = bufio.NewReader(stdout).ReadString('\n')
+	if err != nil {
+		d.Close()
+		return nil, fmt.Errorf("reading dbus-daemon address: %w", err)
+	}
+	d.address = strings.TrimSpace(d.address)
+
+	d.conn, err = dbus.Connect(d.address)
+	if err != nil {
+		d.Close()
+		return nil, err
+	}
+
+	err = d.export()
+	if err != nil {
+		d.Close()
+		return nil, err
+	}
+
+	return d, nil
+}
+
+// Close stops the fake server and the private bus
+func (d *Daemon) Close() error {
+	if d.conn != nil {
+		d.conn.Close()
+	}
+
+	if d.cmd != nil {
+		_ = d.cmd.Process.Kill()
+		_ = d.cmd.Wait()
+	}
+
+	return os.RemoveAll(d.dir)
+}
+
+// Address returns the D-Bus address of the private bus
+func (d *Daemon) Address() string {
+	return d.address
+}
+
+// Conn opens a new client connection to the private bus, for use with avahi.ServerNew
+func (d *Daemon) Conn() (*dbus.Conn, error) {
+	return dbus.Connect(d.address)
+}
+
+func (d *Daemon) export() error {
+	xml := introspectionServer
+	if d.server2 {
+		xml = introspectionServer2
+	}
+
+	err := d.conn.Export(introspect.Introspectable(xml), "/", "org.freedesktop.DBus.Introspectable")
+	if err != nil {
+		return err
+	}
+
+	err = d.conn.Export(&server{d}, "/", "org.freedesktop.Avahi.Server")
+	if err != nil {
+		return err
+	}
+
+	if d.server2 {
+		err = d.conn.Export(&server2{d}, "/", "org.freedesktop.Avahi.Server2")
+		if err != nil {
+			return err
+		}
+	}
+
+	reply, err := d.conn.RequestName("org.freedesktop.Avahi", dbus.NameFlagDoNotQueue)
+	if err != nil {
+		return err
+	}
+
+	if reply != dbus.RequestNameReplyPrimaryOwner && reply != dbus.RequestNameReplyAlreadyOwner {
+		return fmt.Errorf("cannot own org.freedesktop.Avahi: %v", reply)
+	}
+
+	return nil
+}
+
+func (d *Daemon) unexport() error {
+	d.mutex.Lock()
+	for path, o := range d.objects {
+		o.free()
+		delete(d.objects, path)
+	}
+	d.clients = make(map[string]*client)
+	d.mutex.Unlock()
+
+	for _, iface := range []string{
+		"org.freedesktop.DBus.Introspectable",
+		"org.freedesktop.Avahi.Server",
+		"org.freedesktop.Avahi.Server2",
+	} {
+		_ = d.conn.Export(nil, "/", iface)
+	}
+
+	_, err := d.conn.ReleaseName("org.freedesktop.Avahi")
+
+	return err
+}
+
+// DisableServer2 makes the daemon behave like Avahi releases before 0.8,
+// which do not implement the org.freedesktop.Avahi.Server2 interface.
+// Like Restart, it drops all client objects.
+func (d *Daemon) DisableServer2() error {
+	err := d.unexport()
+	if err != nil {
+		return err
+	}
+
+	d.server2 = false
+
+	return d.export()
+}
+
+// Restart simulates a restart of avahi-daemon. The daemon leaves the bus, all objects
+// created by clients are dropped and everything published by entry groups is withdrawn.
+// Scripted services, hosts, records and domains are kept.
+func (d *Daemon) Restart() error {
+	err := d.unexport()
+	if err != nil {
+		return err
+	}
+
+	return d.export()
+}
+
+// SetResolveTimeout sets the time after which resolvers report a failure
+// when nothing matching has been found. It defaults to one second.
+func (d *Daemon) SetResolveTimeout(timeout time.Duration) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	d.resolveTimeout = timeout
+}
+
+// SetState changes the server state and emits the StateChanged signal with the error name
+// avahi-daemon sends: ErrCollision for ServerCollision, err or ErrFailure for ServerFailure,
+// and Success otherwise
+func (d *Daemon) SetState(state int32, err *avahi.Error) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	errorName := errorSuccess
+	switch state {
+	case avahi.ServerCollision:
+		errorName = avahi.ErrCollision.Name
+	case avahi.ServerFailure:
+		errorName = avahi.ErrFailure.Name
+		if err != nil {
+			errorName = err.Name
+		}
+	}
+
+	d.state = state
+	d.emit("", "/", "org.freedesktop.Avahi.Server", "StateChanged", state, errorName)
+}
+
+// SetHostName changes the host name of the fake host
+func (d *Daemon) SetHostName(name string) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	d.hostName = name
+}
+
+// SetInterfaces replaces the network interfaces of the fake host, mapping index to name
+func (d *Daemon) SetInterfaces(interfaces map[int32]string) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	d.interfaces = interfaces
+}
+
+// AddCollision makes every entry group containing a service or address with the given
+// name fail with EntryGroupCollision, as if another host on the network used the name.
+func (d *Daemon) AddCollision(name string) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	d.collisions[strings.ToLower(name)] = true
+}
+
+// RemoveCollision removes a name added with AddCollision
+func (d *Daemon) RemoveCollision(name string) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	delete(d.collisions, strings.ToLower(name))
+}
+
+// AddService announces a resolved service on the fake network. A service with the same
+// interface, protocol, name, type and domain is replaced, which makes all resolvers for
+// it report the new data. Interface and Protocol must not be unspecified.
+func (d *Daemon) AddService(s avahi.Service) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	for _, old := range d.services {
+		if old.group == nil && sameService(old.Service, s) {
+			old.Service = s
+			d.serviceChanged(old)
+			return
+		}
+	}
+
+	d.addService(&service{Service: s})
+}
+
+// RemoveService withdraws a service added with AddService
+func (d *Daemon) RemoveService(s avahi.Service) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	for _, old := range d.services {
+		if old.group == nil && sameService(old.Service, s) {
+			d.removeService(old)
+			return
+		}
+	}
+}
+
+// SetResolvable makes a service added with AddService resolvable or not, like an instance
+// whose host stops answering. Browsers find it either way. Resolvers that found it report
+// a failure once it becomes unresolvable, and waiting resolvers find it once it is resolvable.
+func (d *Daemon) SetResolvable(s avahi.Service, resolvable bool) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	for _, old := range d.services {
+		if old.group != nil || !sameService(old.Service, s) {
+			continue
+		}
+
+		old.unresolvable = !resolvable
+
+		if resolvable {
+			d.serviceChanged(old)
+			return
+		}
+
+		for _, o := range d.objects {
+			if r, ok := o.(*resolver); ok && r.started && r.found && r.kind == serviceResolver && r.matchService(old) {
+				r.found = false
+				r.emit("Failure", errTimeout.Name)
+			}
+		}
+
+		return
+	}
+}
+
+// FailBrowsers makes all running browsers report err, like avahi-daemon does on
+// internal errors. The browsers report nothing afterwards.
+func (d *Daemon) FailBrowsers(err *avahi.Error) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	for _, o := range d.objects {
+		if b, ok := o.(*browser); ok && b.started {
+			b.started = false
+			b.emit("Failure", err.Name)
+		}
+	}
+}
+
+// Services returns all services currently announced on the fake network,
+// including those published by entry groups
+func (d *Daemon) Services() []avahi.Service {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	services := make([]avahi.Service, 0, len(d.services))
+	for _, s := range d.services {
+		services = append(services, s.Service)
+	}
+
+	return services
+}
+
+// AddHost announces a host name and address on the fake network
+func (d *Daemon) AddHost(h avahi.HostName) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	d.hosts = append(d.hosts, &host{HostName: h})
+}
+
+// RemoveHost withdraws a host name and address added with AddHost
+func (d *Daemon) RemoveHost(h avahi.HostName) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	for i, old := range d.hosts {
+		if old.group == nil && old.HostName == h {
+			d.hosts = append(d.hosts[:i], d.hosts[i+1:]...)
+			return
+		}
+	}
+}
+
+// AddRecord announces a resource record on the fake network
+func (d *Daemon) AddRecord(r avahi.Record) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	d.addRecord(&record{Record: r})
+}
+
+// RemoveRecord withdraws a resource record added with AddRecord
+func (d *Daemon) RemoveRecord(r avahi.Record) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	for _, old := range d.records {
+		if old.group == nil && sameRecord(old.Record, r) {
+			d.removeRecord(old)
+			return
+		}
+	}
+}
+
+// AddDomain announces a browse or registration domain
+func (d *Daemon) AddDomain(domain avahi.Domain) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	d.domains = append(d.domains, domain)
+
+	for _, o := range d.objects {
+		if b, ok := o.(*browser); ok && b.kind == domainBrowser && b.started && b.matchDomain(domain) {
+			b.emit("ItemNew", domain.Interface, domain.Protocol, domain.Domain, domain.Flags)
+		}
+	}
+}
+
+// RemoveDomain withdraws a domain added with AddDomain
+func (d *Daemon) RemoveDomain(domain avahi.Domain) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	for i, old := range d.domains {
+		if old == domain {
+			d.domains = append(d.domains[:i], d.domains[i+1:]...)
+
+			for _, o := range d.objects {
+				if b, ok := o.(*browser); ok && b.kind == domainBrowser && b.started && b.matchDomain(domain) {
+					b.emit("ItemRemove", domain.Interface, domain.Protocol, domain.Domain, domain.Flags)
+				}
+			}
+
+			return
+		}
+	}
+}
+
+// emit sends a signal to dest, or broadcasts it if dest is empty, like avahi-daemon does
+func (d *Daemon) emit(dest string, path dbus.ObjectPath, iface, member string, args ...interface{}) {
+	msg := &dbus.Message{
+		Type: dbus.TypeSignal,
+		Headers: map[dbus.HeaderField]dbus.Variant{
+			dbus.FieldPath:      dbus.MakeVariant(path),
+			dbus.FieldInterface: dbus.MakeVariant(iface),
+			dbus.FieldMember:    dbus.MakeVariant(member),
+		},
+		Body: args,
+	}
+
+	if dest != "" {
+		msg.Headers[dbus.FieldDestination] = dbus.MakeVariant(dest)
+	}
+
+	if len(args) > 0 {
+		msg.Headers[dbus.FieldSignature] = dbus.MakeVariant(dbus.SignatureOf(args...))
+	}
+
+	d.conn.Send(msg, nil)
+}
+
+// newObjectPath returns a path for a new object of a client. The caller must hold d.mutex.
+func (d *Daemon) newObjectPath(sender dbus.Sender, kind string) dbus.ObjectPath {
+	c, ok := d.clients[string(sender)]
+	if !ok {
+		c = &client{id: len(d.clients) + 1}
+		d.clients[string(sender)] = c
+	}
+
+	c.counter++
+
+	return dbus.ObjectPath(fmt.Sprintf("/Client%d/%s%d", c.id, kind, c.counter))
+}
+
+func (d *Daemon) fqdn() string {
+	return d.hostName + "." + d.domainName
+}
+
+func sameService(a, b avahi.Service) bool {
+	return a.Interface == b.Interface && a.Protocol == b.Protocol &&
+		strings.EqualFold(a.Name, b.Name) &&
+		strings.EqualFold(a.Type, b.Type) &&
+		strings.EqualFold(a.Domain, b.Domain)
+}
+
+func sameRecord(a, b avahi.Record) bool {
+	return a.Interface == b.Interface && a.Protocol == b.Protocol &&
+		strings.EqualFold(a.Name, b.Name) && a.Class == b.Class && a.Type == b.Type &&
+		string(a.Rdata) == string(b.Rdata)
+}
+
+// addService announces s to all browsers. The caller must hold d.mutex.
+func (d *Daemon) addService(s *service) {
+	d.services = append(d.services, s)
+
+	for _, o := range d.objects {
+		if b, ok := o.(*browser); ok && b.started {
+			b.serviceAdded(s)
+		}
+	}
+
+	d.serviceChanged(s)
+}
+
+// serviceChanged makes all resolvers report s again. The caller must hold d.mutex.
+func (d *Daemon) serviceChanged(s *service) {
+	if s.unresolvable {
+		return
+	}
+
+	for _, o := range d.objects {
+		if r, ok := o.(*resolver); ok && r.started && r.kind == serviceResolver && r.matchService(s) {
+			r.foundService(s)
+		}
+	}
+}
+
+// removeService withdraws s from all browsers. The caller must hold d.mutex.
+func (d *Daemon) removeService(s *service) {
+	for i, old := range d.services {
+		if old == s {
+			d.services = append(d.services[:i], d.services[i+1:]...)
+			break
+		}
+	}
+
+	for _, o := range d.objects {
+		if b, ok := o.(*browser); ok && b.started {
+			b.serviceRemoved(s)
+		}
+	}
+}
+
+// addRecord announces r to all record browsers. The caller must hold d.mutex.
+func (d *Daemon) addRecord(r *record) {
+	d.records = append(d.records, r)
+
+	for _, o := range d.objects {
+		if b, ok := o.(*browser); ok && b.started && b.kind == recordBrowser && b.matchRecord(r.Record) {
+			b.emitRecord("ItemNew", r.Record)
+		}
+	}
+}
+
+// removeRecord withdraws r from all record browsers. The caller must hold d.mutex.
+func (d *Daemon) removeRecord(r *record) {
+	for i, old := range d.records {
+		if old == r {
+			d.records = append(d.records[:i], d.records[i+1:]...)
+			break
+		}
+	}
+
+	for _, o := range d.objects {
+		if b, ok := o.(*browser); ok && b.started && b.kind == recordBrowser && b.matchRecord(r.Record) {
+			b.emitRecord("ItemRemove", r.Record)
+		}
+	}
+}
@@ -0,0 +1,137 @@
+package avahitest_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/holoplot/go-avahi"
+	"github.com/holoplot/go-avahi/avahitest"
+)
+
+var testService = avahi.Service{
+	Interface: 2,
+	Protocol:  avahi.ProtoInet,
+	Name:      "Printer",
+	Type:      "_ipp._tcp",
+	Domain:    "local",
+	Host:      "printer.local",
+	Aprotocol: avahi.ProtoInet,
+	Address:   "192.168.1.20",
+	Port:      631,
+	Txt:       [][]byte{[]byte("txtvers=1")},
+	Flags:     avahi.LookupResultMulticast,
+}
+
+func TestServer(t *testing.T) {
+	_, s := avahitest.NewServer(t)
+
+	name, err := s.GetHostName()
+	if err != nil || name != "fakehost" {
+		t.Fatalf("GetHostName() returned %q, %v", name, err)
+	}
+
+	index, err := s.GetNetworkInterfaceIndexByName("eth0")
+	if err != nil || index != 2 {
+		t.Fatalf("GetNetworkInterfaceIndexByName() returned %d, %v", index, err)
+	}
+
+	alternative, err := s.GetAlternativeServiceName("Printer #2")
+	if err != nil || alternative != "Printer #3" {
+		t.Fatalf("GetAlternativeServiceName() returned %q, %v", alternative, err)
+	}
+
+	eg, err := s.EntryGroupNew()
+	if err != nil {
+		t.Fatalf("EntryGroupNew() failed: %v", err)
+	}
+
+	empty, err := eg.IsEmpty()
+	if err != nil || !empty {
+		t.Fatalf("IsEmpty() returned %v, %v", empty, err)
+	}
+
+	err = eg.Commit()
+	if !errors.Is(err, avahi.ErrIsEmpty) {
+		t.Fatalf("Commit() of an empty group returned %v", err)
+	}
+
+	err = eg.AddServiceSubtype(avahi.InterfaceUnspec, avahi.ProtoUnspec, 0, "Missing", "_ipp._tcp", "", "_color._sub._ipp._tcp")
+	if !errors.Is(err, avahi.ErrNotFound) {
+		t.Fatalf("AddServiceSubtype() for a missing service returned %v", err)
+	}
+}
+
+func TestEntryGroupCollision(t *testing.T) {
+	d, s := avahitest.NewServer(t)
+
+	d.AddCollision("Printer")
+
+	eg, err := s.EntryGroupNew()
+	if err != nil {
+		t.Fatalf("EntryGroupNew() failed: %v", err)
+	}
+
+	err = eg.AddService(avahi.InterfaceUnspec, avahi.ProtoUnspec, 0, "Printer", "_ipp._tcp", "", "", 631, nil)
+	if err != nil {
+		t.Fatalf("AddService() failed: %v", err)
+	}
+
+	err = eg.Commit()
+	if err != nil {
+		t.Fatalf("Commit() failed: %v", err)
+	}
+
+	for _, expected := range []avahi.EntryGroupState{
+		{State: avahi.EntryGroupRegistering, Error: "org.freedesktop.Avahi.Success"},
+		{State: avahi.EntryGroupCollision, Error: avahi.ErrCollision.Name},
+	} {
+		select {
+		case state := <-eg.StateChangeChannel:
+			if state != expected {
+				t.Fatalf("StateChangeChannel delivered %+v, expected %+v", state, expected)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("no entry group state change")
+		}
+	}
+}
+
+func TestSetResolvable(t *testing.T) {
+	d, s := avahitest.NewServer(t)
+
+	d.AddService(testService)
+	d.SetResolvable(testService, false)
+
+	_, err := s.ResolveService(avahi.InterfaceUnspec, avahi.ProtoUnspec, "Printer", "_ipp._tcp", "local", avahi.ProtoUnspec, 0)
+	if !errors.Is(err, avahi.ErrTimeout) {
+		t.Fatalf("ResolveService() of an unresolvable service returned %v", err)
+	}
+
+	r, err := s.ServiceResolverNew(avahi.InterfaceUnspec, avahi.ProtoUnspec, "Printer", "_ipp._tcp", "local", avahi.ProtoUnspec, 0)
+	if err != nil {
+		t.Fatalf("ServiceResolverNew() failed: %v", err)
+	}
+
+	d.SetResolvable(testService, true)
+
+	select {
+	case service := <-r.FoundChannel:
+		if service.Address != testService.Address {
+			t.Fatalf("resolved %+v", service)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("service not resolved once resolvable")
+	}
+
+	d.SetResolvable(testService, false)
+
+	select {
+	case err := <-r.FailureChannel:
+		if !errors.Is(err, avahi.ErrTimeout) {
+			t.Fatalf("FailureChannel delivered %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no failure once unresolvable")
+	}
+}
@@ -0,0 +1,359 @@
+package avahitest
+
+import (
+	"net"
+	"strings"
+
+	dbus "github.com/godbus/dbus/v5"
+	"github.com/holoplot/go-avahi"
+)
+
+// entryGroup implements org.freedesktop.Avahi.EntryGroup
+type entryGroup struct {
+	d     *Daemon
+	path  dbus.ObjectPath
+	owner string
+	state int32
+
+	services []*service
+	hosts    []*host
+	records  []*record
+}
+
+func (g *entryGroup) free() {
+	g.withdraw()
+}
+
+// emitState changes the state and sends the name of err, or Success if it is nil, like avahi-daemon
+func (g *entryGroup) emitState(state int32, err *dbus.Error) {
+	errorName := errorSuccess
+	if err != nil {
+		errorName = err.Name
+	}
+
+	g.state = state
+	g.d.emit(g.owner, g.path, "org.freedesktop.Avahi.EntryGroup", "StateChanged", state, errorName)
+}
+
+// withdraw removes everything the group published from the network. The caller must hold d.mutex.
+func (g *entryGroup) withdraw() {
+	if g.state != avahi.EntryGroupEstablished {
+		return
+	}
+
+	for _, s := range g.services {
+		g.d.removeService(s)
+	}
+
+	for _, h := range g.hosts {
+		for i, old := range g.d.hosts {
+			if old == h {
+				g.d.hosts = append(g.d.hosts[:i], g.d.hosts[i+1:]...)
+				break
+			}
+		}
+	}
+
+	for _, r := range g.records {
+		g.d.removeRecord(r)
+	}
+}
+
+// collides reports whether any entry of the group conflicts with the network. The caller must hold d.mutex.
+func (g *entryGroup) collides() bool {
+	for _, s := range g.services {
+		if g.d.collisions[strings.ToLower(s.Name)] {
+			return true
+		}
+
+		for _, other := range g.d.services {
+			if other.group != g && strings.EqualFold(other.Name, s.Name) &&
+				strings.EqualFold(other.Type, s.Type) && strings.EqualFold(other.Domain, s.Domain) {
+				return true
+			}
+		}
+	}
+
+	for _, h := range g.hosts {
+		if g.d.collisions[strings.ToLower(h.Name)] {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (g *entryGroup) Free() *dbus.Error {
+	g.d.mutex.Lock()
+	defer g.d.mutex.Unlock()
+
+	return g.d.freeObject(g.path, "org.freedesktop.Avahi.EntryGroup")
+}
+
+func (g *entryGroup) Commit() *dbus.Error {
+	g.d.mutex.Lock()
+	defer g.d.mutex.Unlock()
+
+	if g.state == avahi.EntryGroupRegistering || g.state == avahi.EntryGroupEstablished {
+		return errBadState
+	}
+
+	if len(g.services) == 0 && len(g.hosts) == 0 && len(g.records) == 0 {
+		return errIsEmpty
+	}
+
+	g.emitState(avahi.EntryGroupRegistering, nil)
+
+	if g.collides() {
+		g.emitState(avahi.EntryGroupCollision, errCollision)
+		return nil
+	}
+
+	g.emitState(avahi.EntryGroupEstablished, nil)
+
+	g.d.hosts = append(g.d.hosts, g.hosts...)
+
+	for _, s := range g.services {
+		s.Address, s.Aprotocol = g.d.addressOf(s.Host, s.Protocol)
+		g.d.addService(s)
+	}
+
+	for _, r := range g.records {
+		g.d.addRecord(r)
+	}
+
+	return nil
+}
+
+func (g *entryGroup) Reset() *dbus.Error {
+	g.d.mutex.Lock()
+	defer g.d.mutex.Unlock()
+
+	g.withdraw()
+
+	g.services = nil
+	g.hosts = nil
+	g.records = nil
+
+	g.emitState(avahi.EntryGroupUncommited, nil)
+
+	return nil
+}
+
+func (g *entryGroup) GetState() (int32, *dbus.Error) {
+	g.d.mutex.Lock()
+	defer g.d.mutex.Unlock()
+
+	return g.state, nil
+}
+
+func (g *entryGroup) IsEmpty() (bool, *dbus.Error) {
+	g.d.mutex.Lock()
+	defer g.d.mutex.Unlock()
+
+	return len(g.services) == 0 && len(g.hosts) == 0 && len(g.records) == 0, nil
+}
+
+// normalize replaces unspecified interfaces and protocols, as the fake network
+// only knows about concrete ones. The caller must hold d.mutex.
+func (d *Daemon) normalize(iface, protocol int32) (int32, int32) {
+	if iface == avahi.InterfaceUnspec {
+		iface = 0
+		for index := range d.interfaces {
+			if iface == 0 || index < iface {
+				iface = index
+			}
+		}
+	}
+
+	if protocol == avahi.ProtoUnspec {
+		protocol = avahi.ProtoInet
+	}
+
+	return iface, protocol
+}
+
+// addressOf returns an address of a local host name. The caller must hold d.mutex.
+func (d *Daemon) addressOf(name string, protocol int32) (string, int32) {
+	if h := d.findHost(avahi.InterfaceUnspec, avahi.ProtoUnspec, name, protocol); h != nil {
+		return h.Address, h.Aprotocol
+	}
+
+	if protocol == avahi.ProtoInet6 {
+		return "::1", avahi.ProtoInet6
+	}
+
+	return "127.0.0.1", avahi.ProtoInet
+}
+
+func (g *entryGroup) editable() *dbus.Error {
+	if g.state == avahi.EntryGroupRegistering || g.state == avahi.EntryGroupEstablished {
+		return errBadState
+	}
+
+	return nil
+}
+
+func (g *entryGroup) findService(iface, protocol int32, name, serviceType, domain string) *service {
+	for _, s := range g.services {
+		if matchInterface(iface, s.Interface) && matchProtocol(protocol, s.Protocol) &&
+			strings.EqualFold(name, s.Name) && strings.EqualFold(serviceType, s.Type) &&
+			matchDomain(domain, s.Domain) {
+			return s
+		}
+	}
+
+	return nil
+}
+
+func (g *entryGroup) AddService(iface, protocol int32, flags uint32, name, serviceType, domain, host string, port uint16, txt [][]byte) *dbus.Error {
+	g.d.mutex.Lock()
+	defer g.d.mutex.Unlock()
+
+	if err := g.editable(); err != nil {
+		return err
+	}
+
+	if name == "" || !strings.HasPrefix(serviceType, "_") {
+		return errInvalidArgument
+	}
+
+	iface, protocol = g.d.normalize(iface, protocol)
+
+	if domain == "" {
+		domain = g.d.domainName
+	}
+
+	if host == "" {
+		host = g.d.fqdn()
+	}
+
+	if g.findService(iface, protocol, name, serviceType, domain) != nil {
+		return errCollision
+	}
+
+	g.services = append(g.services, &service{
+		Service: avahi.Service{
+			Interface: iface,
+			Protocol:  protocol,
+			Name:      name,
+			Type:      serviceType,
+			Domain:    domain,
+			Host:      host,
+			Port:      port,
+			Txt:       txt,
+		},
+		group: g,
+	})
+
+	return nil
+}
+
+func (g *entryGroup) AddServiceSubtype(iface, protocol int32, flags uint32, name, serviceType, domain, subtype string) *dbus.Error {
+	g.d.mutex.Lock()
+	defer g.d.mutex.Unlock()
+
+	if err := g.editable(); err != nil {
+		return err
+	}
+
+	s := g.findService(iface, protocol, name, serviceType, domain)
+	if s == nil {
+		return errNotFound
+	}
+
+	if !strings.Contains(subtype, "._sub.") {
+		return errInvalidArgument
+	}
+
+	s.subtypes = append(s.subtypes, subtype)
+
+	return nil
+}
+
+func (g *entryGroup) UpdateServiceTxt(iface, protocol int32, flags uint32, name, serviceType, domain string, txt [][]byte) *dbus.Error {
+	g.d.mutex.Lock()
+	defer g.d.mutex.Unlock()
+
+	s := g.findService(iface, protocol, name, serviceType, domain)
+	if s == nil {
+		return errNotFound
+	}
+
+	s.Txt = txt
+
+	if g.state == avahi.EntryGroupEstablished {
+		g.d.serviceChanged(s)
+	}
+
+	return nil
+}
+
+func (g *entryGroup) AddAddress(iface, protocol int32, flags uint32, name, address string) *dbus.Error {
+	g.d.mutex.Lock()
+	defer g.d.mutex.Unlock()
+
+	if err := g.editable(); err != nil {
+		return err
+	}
+
+	ip := net.ParseIP(address)
+	if ip == nil || name == "" {
+		return errInvalidArgument
+	}
+
+	if strings.HasPrefix(name, ".") || strings.Contains(name, "..") {
+		return errInvalidHostName
+	}
+
+	aprotocol := int32(avahi.ProtoInet6)
+	if ip.To4() != nil {
+		aprotocol = avahi.ProtoInet
+	}
+
+	iface, protocol = g.d.normalize(iface, protocol)
+
+	g.hosts = append(g.hosts, &host{
+		HostName: avahi.HostName{
+			Interface: iface,
+			Protocol:  protocol,
+			Name:      strings.TrimSuffix(name, "."),
+			Aprotocol: aprotocol,
+			Address:   address,
+			Flags:     avahi.LookupResultLocal,
+		},
+		group: g,
+	})
+
+	return nil
+}
+
+func (g *entryGroup) AddRecord(iface, protocol int32, flags uint32, name string, class, recordType uint16, ttl uint32, rdata []byte) *dbus.Error {
+	g.d.mutex.Lock()
+	defer g.d.mutex.Unlock()
+
+	if err := g.editable(); err != nil {
+		return err
+	}
+
+	if name == "" {
+		return errInvalidArgument
+	}
+
+	iface, protocol = g.d.normalize(iface, protocol)
+
+	g.records = append(g.records, &record{
+		Record: avahi.Record{
+			Interface: iface,
+			Protocol:  protocol,
+			Name:      strings.TrimSuffix(name, "."),
+			Class:     class,
+			Type:      recordType,
+			Rdata:     rdata,
+			Flags:     avahi.LookupResultLocal,
+		},
+		group: g,
+	})
+
+	return nil
+}
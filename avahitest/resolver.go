@@ -0,0 +1,157 @@
+package avahitest
+
+import (
+	"strings"
+	"time"
+
+	dbus "github.com/godbus/dbus/v5"
+	"github.com/holoplot/go-avahi"
+)
+
+const (
+	serviceResolver  = "ServiceResolver"
+	hostNameResolver = "HostNameResolver"
+	addressResolver  = "AddressResolver"
+)
+
+// resolver implements the ServiceResolver, HostNameResolver and AddressResolver interfaces
+type resolver struct {
+	d       *Daemon
+	kind    string
+	path    dbus.ObjectPath
+	owner   string
+	started bool
+	found   bool
+	timer   *time.Timer
+
+	iface       int32
+	protocol    int32
+	name        string
+	serviceType string
+	domain      string
+	address     string
+	aprotocol   int32
+	flags       uint32
+}
+
+// newResolver registers r. Resolvers created through the Server interface start right away,
+// prepared ones on Start. The caller must not hold d.mutex.
+func (d *Daemon) newResolver(sender dbus.Sender, r *resolver, start bool) (dbus.ObjectPath, *dbus.Error) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	r.d = d
+	r.owner = string(sender)
+	r.path = d.newObjectPath(sender, r.kind)
+
+	err := d.exportObject(r, r.path, r.interfaceName())
+	if err != nil {
+		return "", err
+	}
+
+	if start {
+		r.start()
+	}
+
+	return r.path, nil
+}
+
+func (r *resolver) interfaceName() string {
+	return "org.freedesktop.Avahi." + r.kind
+}
+
+func (r *resolver) Free() *dbus.Error {
+	r.d.mutex.Lock()
+	defer r.d.mutex.Unlock()
+
+	return r.d.freeObject(r.path, r.interfaceName())
+}
+
+func (r *resolver) Start() *dbus.Error {
+	r.d.mutex.Lock()
+	defer r.d.mutex.Unlock()
+
+	if r.started {
+		return errBadState
+	}
+
+	r.start()
+
+	return nil
+}
+
+func (r *resolver) free() {
+	r.started = false
+
+	if r.timer != nil {
+		r.timer.Stop()
+	}
+}
+
+func (r *resolver) emit(member string, args ...interface{}) {
+	r.d.emit(r.owner, r.path, r.interfaceName(), member, args...)
+}
+
+// start reports a match if one is known, and otherwise arms the timeout.
+// Service resolvers keep reporting changes until they are freed.
+// The caller must hold d.mutex.
+func (r *resolver) start() {
+	r.started = true
+
+	switch r.kind {
+	case serviceResolver:
+		if s := r.d.findService(r.iface, r.protocol, r.name, r.serviceType, r.domain); s != nil {
+			r.foundService(s)
+		}
+
+	case hostNameResolver:
+		if h := r.d.findHost(r.iface, r.protocol, r.name, r.aprotocol); h != nil {
+			r.found = true
+			r.emit("Found", h.Interface, h.Protocol, h.Name, h.Aprotocol, h.Address, h.Flags)
+		}
+
+	case addressResolver:
+		if h := r.d.findAddress(r.iface, r.protocol, r.address); h != nil {
+			r.found = true
+			r.emit("Found", h.Interface, h.Protocol, h.Aprotocol, h.Address, h.Name, h.Flags)
+		}
+	}
+
+	if !r.found {
+		r.timer = time.AfterFunc(r.d.resolveTimeout, func() {
+			r.d.mutex.Lock()
+			defer r.d.mutex.Unlock()
+
+			if r.started && !r.found {
+				r.emit("Failure", errTimeout.Name)
+			}
+		})
+	}
+}
+
+func (r *resolver) matchService(s *service) bool {
+	return matchInterface(r.iface, s.Interface) && matchProtocol(r.protocol, s.Protocol) &&
+		strings.EqualFold(r.name, s.Name) && strings.EqualFold(r.serviceType, s.Type) &&
+		matchDomain(r.domain, s.Domain)
+}
+
+// foundService reports s. The caller must hold d.mutex.
+func (r *resolver) foundService(s *service) {
+	r.found = true
+
+	flags := uint32(avahi.LookupResultMulticast)
+	if s.group != nil {
+		flags = avahi.LookupResultLocal
+		if s.group.owner == r.owner {
+			flags |= avahi.LookupResultOurOwn
+		}
+	}
+
+	txt := s.Txt
+	if txt == nil {
+		txt = [][]byte{}
+	}
+
+	r.emit("Found", s.Interface, s.Protocol, s.Name, s.Type, s.Domain, s.Host,
+		s.Aprotocol, s.Address, s.Port, txt, flags)
+}
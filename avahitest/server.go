@@ -0,0 +1,376 @@
+package avahitest
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	dbus "github.com/godbus/dbus/v5"
+	"github.com/holoplot/go-avahi"
+)
+
+// D-Bus errors as sent by avahi-daemon
+var (
+	errBadState        = dbus.NewError("org.freedesktop.Avahi.BadStateError", []interface{}{"Invalid state"})
+	errCollision       = dbus.NewError("org.freedesktop.Avahi.CollisionError", []interface{}{"Local name collision"})
+	errInvalidArgument = dbus.NewError("org.freedesktop.Avahi.InvalidArgumentError", []interface{}{"Invalid argument"})
+	errInvalidHostName = dbus.NewError("org.freedesktop.Avahi.InvalidHostNameError", []interface{}{"Invalid host name"})
+	errInvalidObject   = dbus.NewError("org.freedesktop.Avahi.InvalidObjectError", []interface{}{"The object passed in was not valid"})
+	errIsEmpty         = dbus.NewError("org.freedesktop.Avahi.IsEmptyError", []interface{}{"Is empty"})
+	errNotFound        = dbus.NewError("org.freedesktop.Avahi.NotFoundError", []interface{}{"Not found"})
+	errOS              = dbus.NewError("org.freedesktop.Avahi.OSError", []interface{}{"OS Error"})
+	errTimeout         = dbus.NewError("org.freedesktop.Avahi.TimeoutError", []interface{}{"Timeout reached"})
+)
+
+// errorSuccess is the error name avahi-daemon sends in StateChanged signals of states without error
+const errorSuccess = "org.freedesktop.Avahi.Success"
+
+// server implements org.freedesktop.Avahi.Server
+type server struct {
+	d *Daemon
+}
+
+// server2 implements org.freedesktop.Avahi.Server2
+type server2 struct {
+	d *Daemon
+}
+
+func (s *server) GetVersionString() (string, *dbus.Error) {
+	return "avahi 0.8", nil
+}
+
+func (s *server) GetAPIVersion() (uint32, *dbus.Error) {
+	return 516, nil
+}
+
+func (s *server) GetHostName() (string, *dbus.Error) {
+	s.d.mutex.Lock()
+	defer s.d.mutex.Unlock()
+
+	return s.d.hostName, nil
+}
+
+func (s *server) SetHostName(name string) *dbus.Error {
+	s.d.mutex.Lock()
+	defer s.d.mutex.Unlock()
+
+	if name == "" || strings.Contains(name, ".") {
+		return errInvalidArgument
+	}
+
+	s.d.hostName = name
+
+	return nil
+}
+
+func (s *server) GetHostNameFqdn() (string, *dbus.Error) {
+	s.d.mutex.Lock()
+	defer s.d.mutex.Unlock()
+
+	return s.d.fqdn(), nil
+}
+
+func (s *server) GetDomainName() (string, *dbus.Error) {
+	s.d.mutex.Lock()
+	defer s.d.mutex.Unlock()
+
+	return s.d.domainName, nil
+}
+
+func (s *server) IsNSSSupportAvailable() (bool, *dbus.Error) {
+	return false, nil
+}
+
+func (s *server) GetState() (int32, *dbus.Error) {
+	s.d.mutex.Lock()
+	defer s.d.mutex.Unlock()
+
+	return s.d.state, nil
+}
+
+func (s *server) GetLocalServiceCookie() (uint32, *dbus.Error) {
+	return s.d.cookie, nil
+}
+
+var alternativeHostNameRegexp = regexp.MustCompile(`^(.*)-(\d+)$`)
+
+func (s *server) GetAlternativeHostName(name string) (string, *dbus.Error) {
+	if m := alternativeHostNameRegexp.FindStringSubmatch(name); m != nil {
+		n, _ := strconv.Atoi(m[2])
+		return fmt.Sprintf("%s-%d", m[1], n+1), nil
+	}
+
+	return name + "-2", nil
+}
+
+var alternativeServiceNameRegexp = regexp.MustCompile(`^(.*) #(\d+)$`)
+
+func (s *server) GetAlternativeServiceName(name string) (string, *dbus.Error) {
+	if m := alternativeServiceNameRegexp.FindStringSubmatch(name); m != nil {
+		n, _ := strconv.Atoi(m[2])
+		return fmt.Sprintf("%s #%d", m[1], n+1), nil
+	}
+
+	return name + " #2", nil
+}
+
+func (s *server) GetNetworkInterfaceNameByIndex(index int32) (string, *dbus.Error) {
+	s.d.mutex.Lock()
+	defer s.d.mutex.Unlock()
+
+	name, ok := s.d.interfaces[index]
+	if !ok {
+		return "", errOS
+	}
+
+	return name, nil
+}
+
+func (s *server) GetNetworkInterfaceIndexByName(name string) (int32, *dbus.Error) {
+	s.d.mutex.Lock()
+	defer s.d.mutex.Unlock()
+
+	for index, n := range s.d.interfaces {
+		if n == name {
+			return index, nil
+		}
+	}
+
+	return 0, errOS
+}
+
+func (s *server) SetServerName(name string) *dbus.Error {
+	return s.SetHostName(name)
+}
+
+// wait blocks until timeout passed or found reports success
+func (d *Daemon) wait(found func() bool) bool {
+	d.mutex.Lock()
+	deadline := time.Now().Add(d.resolveTimeout)
+	d.mutex.Unlock()
+
+	for {
+		d.mutex.Lock()
+		ok := found()
+		d.mutex.Unlock()
+
+		if ok || time.Now().After(deadline) {
+			return ok
+		}
+
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func (s *server) ResolveHostName(iface, protocol int32, name string, aprotocol int32, flags uint32) (int32, int32, string, int32, string, uint32, *dbus.Error) {
+	var h avahi.HostName
+
+	ok := s.d.wait(func() bool {
+		found := s.d.findHost(iface, protocol, name, aprotocol)
+		if found != nil {
+			h = *found
+		}
+		return found != nil
+	})
+	if !ok {
+		return 0, 0, "", 0, "", 0, errTimeout
+	}
+
+	return h.Interface, h.Protocol, h.Name, h.Aprotocol, h.Address, h.Flags, nil
+}
+
+func (s *server) ResolveAddress(iface, protocol int32, address string, flags uint32) (int32, int32, int32, string, string, uint32, *dbus.Error) {
+	var h avahi.HostName
+
+	ok := s.d.wait(func() bool {
+		found := s.d.findAddress(iface, protocol, address)
+		if found != nil {
+			h = *found
+		}
+		return found != nil
+	})
+	if !ok {
+		return 0, 0, 0, "", "", 0, errTimeout
+	}
+
+	return h.Interface, h.Protocol, h.Aprotocol, h.Address, h.Name, h.Flags, nil
+}
+
+func (s *server) ResolveService(iface, protocol int32, name, serviceType, domain string, aprotocol int32, flags uint32) (
+	int32, int32, string, string, string, string, int32, string, uint16, [][]byte, uint32, *dbus.Error) {
+	var r avahi.Service
+
+	ok := s.d.wait(func() bool {
+		found := s.d.findService(iface, protocol, name, serviceType, domain)
+		if found != nil {
+			r = found.Service
+		}
+		return found != nil
+	})
+	if !ok {
+		return 0, 0, "", "", "", "", 0, "", 0, nil, 0, errTimeout
+	}
+
+	return r.Interface, r.Protocol, r.Name, r.Type, r.Domain, r.Host, r.Aprotocol, r.Address, r.Port, r.Txt, r.Flags, nil
+}
+
+func (s *server) EntryGroupNew(sender dbus.Sender) (dbus.ObjectPath, *dbus.Error) {
+	s.d.mutex.Lock()
+	defer s.d.mutex.Unlock()
+
+	g := &entryGroup{d: s.d, owner: string(sender)}
+	g.path = s.d.newObjectPath(sender, "EntryGroup")
+
+	return g.path, s.d.exportObject(g, g.path, "org.freedesktop.Avahi.EntryGroup")
+}
+
+func (s *server) DomainBrowserNew(sender dbus.Sender, iface, protocol int32, domain string, btype int32, flags uint32) (dbus.ObjectPath, *dbus.Error) {
+	b := &browser{kind: domainBrowser, iface: iface, protocol: protocol, domain: domain, btype: btype, flags: flags}
+	return s.d.newBrowser(sender, b, true)
+}
+
+func (s *server) ServiceTypeBrowserNew(sender dbus.Sender, iface, protocol int32, domain string, flags uint32) (dbus.ObjectPath, *dbus.Error) {
+	b := &browser{kind: serviceTypeBrowser, iface: iface, protocol: protocol, domain: domain, flags: flags}
+	return s.d.newBrowser(sender, b, true)
+}
+
+func (s *server) ServiceBrowserNew(sender dbus.Sender, iface, protocol int32, serviceType, domain string, flags uint32) (dbus.ObjectPath, *dbus.Error) {
+	b := &browser{kind: serviceBrowser, iface: iface, protocol: protocol, serviceType: serviceType, domain: domain, flags: flags}
+	return s.d.newBrowser(sender, b, true)
+}
+
+func (s *server) RecordBrowserNew(sender dbus.Sender, iface, protocol int32, name string, class, recordType uint16, flags uint32) (dbus.ObjectPath, *dbus.Error) {
+	b := &browser{kind: recordBrowser, iface: iface, protocol: protocol, name: name, class: class, recordType: recordType, flags: flags}
+	return s.d.newBrowser(sender, b, true)
+}
+
+func (s *server) ServiceResolverNew(sender dbus.Sender, iface, protocol int32, name, serviceType, domain string, aprotocol int32, flags uint32) (dbus.ObjectPath, *dbus.Error) {
+	r := &resolver{kind: serviceResolver, iface: iface, protocol: protocol, name: name, serviceType: serviceType, domain: domain, aprotocol: aprotocol, flags: flags}
+	return s.d.newResolver(sender, r, true)
+}
+
+func (s *server) HostNameResolverNew(sender dbus.Sender, iface, protocol int32, name string, aprotocol int32, flags uint32) (dbus.ObjectPath, *dbus.Error) {
+	r := &resolver{kind: hostNameResolver, iface: iface, protocol: protocol, name: name, aprotocol: aprotocol, flags: flags}
+	return s.d.newResolver(sender, r, true)
+}
+
+func (s *server) AddressResolverNew(sender dbus.Sender, iface, protocol int32, address string, flags uint32) (dbus.ObjectPath, *dbus.Error) {
+	r := &resolver{kind: addressResolver, iface: iface, protocol: protocol, address: address, flags: flags}
+	return s.d.newResolver(sender, r, true)
+}
+
+func (s *server2) DomainBrowserPrepare(sender dbus.Sender, iface, protocol int32, domain string, btype int32, flags uint32) (dbus.ObjectPath, *dbus.Error) {
+	b := &browser{kind: domainBrowser, iface: iface, protocol: protocol, domain: domain, btype: btype, flags: flags}
+	return s.d.newBrowser(sender, b, false)
+}
+
+func (s *server2) ServiceTypeBrowserPrepare(sender dbus.Sender, iface, protocol int32, domain string, flags uint32) (dbus.ObjectPath, *dbus.Error) {
+	b := &browser{kind: serviceTypeBrowser, iface: iface, protocol: protocol, domain: domain, flags: flags}
+	return s.d.newBrowser(sender, b, false)
+}
+
+func (s *server2) ServiceBrowserPrepare(sender dbus.Sender, iface, protocol int32, serviceType, domain string, flags uint32) (dbus.ObjectPath, *dbus.Error) {
+	b := &browser{kind: serviceBrowser, iface: iface, protocol: protocol, serviceType: serviceType, domain: domain, flags: flags}
+	return s.d.newBrowser(sender, b, false)
+}
+
+func (s *server2) RecordBrowserPrepare(sender dbus.Sender, iface, protocol int32, name string, class, recordType uint16, flags uint32) (dbus.ObjectPath, *dbus.Error) {
+	b := &browser{kind: recordBrowser, iface: iface, protocol: protocol, name: name, class: class, recordType: recordType, flags: flags}
+	return s.d.newBrowser(sender, b, false)
+}
+
+func (s *server2) ServiceResolverPrepare(sender dbus.Sender, iface, protocol int32, name, serviceType, domain string, aprotocol int32, flags uint32) (dbus.ObjectPath, *dbus.Error) {
+	r := &resolver{kind: serviceResolver, iface: iface, protocol: protocol, name: name, serviceType: serviceType, domain: domain, aprotocol: aprotocol, flags: flags}
+	return s.d.newResolver(sender, r, false)
+}
+
+func (s *server2) HostNameResolverPrepare(sender dbus.Sender, iface, protocol int32, name string, aprotocol int32, flags uint32) (dbus.ObjectPath, *dbus.Error) {
+	r := &resolver{kind: hostNameResolver, iface: iface, protocol: protocol, name: name, aprotocol: aprotocol, flags: flags}
+	return s.d.newResolver(sender, r, false)
+}
+
+func (s *server2) AddressResolverPrepare(sender dbus.Sender, iface, protocol int32, address string, flags uint32) (dbus.ObjectPath, *dbus.Error) {
+	r := &resolver{kind: addressResolver, iface: iface, protocol: protocol, address: address, flags: flags}
+	return s.d.newResolver(sender, r, false)
+}
+
+// exportObject exports o at path and registers it. The caller must hold d.mutex.
+func (d *Daemon) exportObject(o object, path dbus.ObjectPath, iface string) *dbus.Error {
+	err := d.conn.Export(o, path, iface)
+	if err != nil {
+		return dbus.MakeFailedError(err)
+	}
+
+	d.objects[path] = o
+
+	return nil
+}
+
+// freeObject unexports the object at path. The caller must hold d.mutex.
+func (d *Daemon) freeObject(path dbus.ObjectPath, iface string) *dbus.Error {
+	o, ok := d.objects[path]
+	if !ok {
+		return errInvalidObject
+	}
+
+	o.free()
+	delete(d.objects, path)
+	_ = d.conn.Export(nil, path, iface)
+
+	return nil
+}
+
+func matchInterface(want, have int32) bool {
+	return want == avahi.InterfaceUnspec || want == have
+}
+
+func matchProtocol(want, have int32) bool {
+	return want == avahi.ProtoUnspec || want == have
+}
+
+func matchDomain(want, have string) bool {
+	if want == "" {
+		want = "local"
+	}
+
+	return strings.EqualFold(strings.TrimSuffix(want, "."), strings.TrimSuffix(have, "."))
+}
+
+// findService returns a service matching the arguments of ResolveService. The caller must hold d.mutex.
+func (d *Daemon) findService(iface, protocol int32, name, serviceType, domain string) *service {
+	for _, s := range d.services {
+		if !s.unresolvable && matchInterface(iface, s.Interface) && matchProtocol(protocol, s.Protocol) &&
+			strings.EqualFold(name, s.Name) && strings.EqualFold(serviceType, s.Type) &&
+			matchDomain(domain, s.Domain) {
+			return s
+		}
+	}
+
+	return nil
+}
+
+// findHost returns an address record for a host name. The caller must hold d.mutex.
+func (d *Daemon) findHost(iface, protocol int32, name string, aprotocol int32) *avahi.HostName {
+	for _, h := range d.hosts {
+		if matchInterface(iface, h.Interface) && matchProtocol(protocol, h.Protocol) &&
+			matchProtocol(aprotocol, h.Aprotocol) &&
+			strings.EqualFold(strings.TrimSuffix(name, "."), h.Name) {
+			return &h.HostName
+		}
+	}
+
+	return nil
+}
+
+// findAddress returns the host record for an address. The caller must hold d.mutex.
+func (d *Daemon) findAddress(iface, protocol int32, address string) *avahi.HostName {
+	for _, h := range d.hosts {
+		if matchInterface(iface, h.Interface) && matchProtocol(protocol, h.Protocol) && h.Address == address {
+			return &h.HostName
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,41 @@
+package avahitest
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/holoplot/go-avahi"
+)
+
+// NewServer starts a Daemon and connects an avahi.Server to it, which are both closed
+// when the test finishes. Resolving missing names times out after 200ms. The test is
+// skipped if there is no dbus-daemon executable.
+func NewServer(t testing.TB) (*Daemon, *avahi.Server) {
+	t.Helper()
+
+	d, err := New()
+	if errors.Is(err, ErrNoBusDaemon) {
+		t.Skip(err)
+	}
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	t.Cleanup(func() { d.Close() })
+
+	d.SetResolveTimeout(200 * time.Millisecond)
+
+	conn, err := d.Conn()
+	if err != nil {
+		t.Fatalf("Conn() failed: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	s, err := avahi.ServerNew(conn)
+	if err != nil {
+		t.Fatalf("ServerNew() failed: %v", err)
+	}
+	t.Cleanup(s.Close)
+
+	return d, s
+}
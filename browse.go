@@ -0,0 +1,124 @@
+package avahi
+
+import "context"
+
+// serviceKey identifies a service instance as reported by a ServiceBrowser
+type serviceKey struct {
+	Interface int32
+	Protocol  int32
+	Name      string
+	Type      string
+	Domain    string
+}
+
+func serviceKeyOf(s Service) serviceKey {
+	return serviceKey{s.Interface, s.Protocol, s.Name, s.Type, s.Domain}
+}
+
+// Browse browses for services of serviceType in domain and resolves every instance found,
+// running at most parallelism resolutions at once (1 if parallelism < 1).
+// It returns the resolved services once the browser reported AllForNow and all pending
+// resolutions have finished. Instances that fail to resolve or are removed while browsing
+// are left out. If ctx ends first, the services resolved so far are returned along with ctx.Err().
+func (c *Server) Browse(ctx context.Context, iface, protocol int32, serviceType, domain string, flags uint32, parallelism int) ([]Service, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	if parallelism < 1 {
+		parallelism = 1
+	}
+
+	b, err := c.ServiceBrowserNew(iface, protocol, serviceType, domain, flags)
+	if err != nil {
+		return nil, err
+	}
+
+	defer c.ServiceBrowserFree(b)
+
+	type result struct {
+		key     serviceKey
+		service Service
+		err     error
+	}
+
+	semaphore := make(chan struct{}, parallelism)
+	resultChannel := make(chan result)
+
+	var keys []serviceKey
+	known := make(map[serviceKey]bool)
+	resolved := make(map[serviceKey]Service)
+	present := make(map[serviceKey]bool)
+	pending := 0
+	allForNow := false
+
+	collect := func() []Service {
+		services := make([]Service, 0, len(resolved))
+		for _, key := range keys {
+			if s, ok := resolved[key]; ok && present[key] {
+				services = append(services, s)
+			}
+		}
+		return services
+	}
+
+	// Let the resolutions still running when returning early finish,
+	// so none of them is left blocking on resultChannel.
+	defer func() {
+		go func(pending int) {
+			for ; pending > 0; pending-- {
+				<-resultChannel
+			}
+		}(pending)
+	}()
+
+	for !allForNow || pending > 0 {
+		select {
+		case s := <-b.AddChannel:
+			key := serviceKeyOf(s)
+			if allForNow || present[key] {
+				continue
+			}
+
+			present[key] = true
+			if !known[key] {
+				known[key] = true
+				keys = append(keys, key)
+			}
+			pending++
+
+			go func() {
+				select {
+				case semaphore <- struct{}{}:
+				case <-ctx.Done():
+					resultChannel <- result{key: key, err: ctx.Err()}
+					return
+				}
+
+				r, err := c.ResolveServiceContext(ctx, s.Interface, s.Protocol, s.Name, s.Type, s.Domain, ProtoUnspec, 0)
+				<-semaphore
+
+				resultChannel <- result{key: key, service: r, err: err}
+			}()
+
+		case s := <-b.RemoveChannel:
+			delete(present, serviceKeyOf(s))
+
+		case <-b.AllForNowChannel:
+			allForNow = true
+
+		case err := <-b.FailureChannel:
+			return collect(), err
+
+		case r := <-resultChannel:
+			pending--
+			if r.err == nil {
+				resolved[r.key] = r.service
+			}
+
+		case <-ctx.Done():
+			return collect(), ctx.Err()
+		}
+	}
+
+	return collect(), nil
+}
@@ -0,0 +1,55 @@
+package avahi_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/holoplot/go-avahi"
+	"github.com/holoplot/go-avahi/avahitest"
+)
+
+func TestBrowse(t *testing.T) {
+	d, s := avahitest.NewServer(t)
+
+	for i := 1; i <= 3; i++ {
+		d.AddService(avahi.Service{
+			Interface: 2,
+			Protocol:  avahi.ProtoInet,
+			Name:      fmt.Sprintf("Printer %d", i),
+			Type:      "_ipp._tcp",
+			Domain:    "local",
+			Host:      "printer.local",
+			Aprotocol: avahi.ProtoInet,
+			Address:   fmt.Sprintf("192.168.1.%d", 20+i),
+			Port:      631,
+		})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	services, err := s.Browse(ctx, avahi.InterfaceUnspec, avahi.ProtoUnspec, "_ipp._tcp", "local", 0, 2)
+	if err != nil {
+		t.Fatalf("Browse() failed: %v", err)
+	}
+
+	if len(services) != 3 {
+		t.Fatalf("Browse() returned %+v", services)
+	}
+
+	for i, service := range services {
+		if service.Name != fmt.Sprintf("Printer %d", i+1) || service.Address != fmt.Sprintf("192.168.1.%d", 21+i) {
+			t.Fatalf("Browse() returned %+v", services)
+		}
+	}
+
+	cancel()
+
+	_, err = s.Browse(ctx, avahi.InterfaceUnspec, avahi.ProtoUnspec, "_ipp._tcp", "local", 0, 2)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("Browse() with a canceled context returned %v", err)
+	}
+}
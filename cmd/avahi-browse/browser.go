@@ -0,0 +1,306 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/holoplot/go-avahi"
+)
+
+const (
+	messageNew = iota
+	messageRemove
+	messageResolved
+	messageResolveFailure
+	messageNewType
+	messageAllForNow
+	messageCacheExhausted
+	messageFailure
+)
+
+// A message is sent by the goroutines forwarding browser and resolver events to the run loop
+type message struct {
+	kind        int
+	service     avahi.Service
+	serviceType avahi.ServiceType
+	err         error
+}
+
+type typeKey struct {
+	serviceType string
+	domain      string
+}
+
+type browser struct {
+	server *avahi.Server
+	cfg    config
+	out    output
+
+	messages chan message
+	quit     chan struct{}
+
+	// browsing holds the service types a browser has been created for
+	browsing map[typeKey]bool
+	// pending counts browsers that have not reported AllForNow and unfinished resolvers
+	pending int
+	// interfaces caches interface names by index
+	interfaces map[int32]string
+}
+
+func newBrowser(server *avahi.Server, cfg config, out output) *browser {
+	return &browser{
+		server:     server,
+		cfg:        cfg,
+		out:        out,
+		messages:   make(chan message),
+		quit:       make(chan struct{}),
+		browsing:   make(map[typeKey]bool),
+		interfaces: make(map[int32]string),
+	}
+}
+
+// send forwards m to the run loop, unless it has returned
+func (b *browser) send(m message) bool {
+	select {
+	case b.messages <- m:
+		return true
+	case <-b.quit:
+		return false
+	}
+}
+
+// finished forwards the AllForNow, CacheExhausted or Failure message m, if it
+// is the first one of a browser that ends browsing with the configured options
+func (b *browser) finished(m message, done *bool) bool {
+	if *done || (m.kind == messageAllForNow && b.cfg.cache) || (m.kind == messageCacheExhausted && !b.cfg.cache) {
+		return true
+	}
+
+	*done = true
+
+	return b.send(m)
+}
+
+func (b *browser) browseServiceTypes(domain string) error {
+	stb, err := b.server.ServiceTypeBrowserNew(avahi.InterfaceUnspec, avahi.ProtoUnspec, domain, 0)
+	if err != nil {
+		return err
+	}
+
+	b.pending++
+
+	go func() {
+		defer b.server.ServiceTypeBrowserFree(stb)
+
+		done := false
+
+		for {
+			var ok bool
+
+			select {
+			case t := <-stb.AddChannel:
+				ok = b.send(message{kind: messageNewType, serviceType: t})
+			case <-stb.RemoveChannel:
+				continue
+			case <-stb.AllForNowChannel:
+				ok = b.finished(message{kind: messageAllForNow}, &done)
+			case <-stb.CacheExhaustedChannel:
+				ok = b.finished(message{kind: messageCacheExhausted}, &done)
+			case err := <-stb.FailureChannel:
+				ok = b.finished(message{kind: messageFailure, err: err}, &done)
+			case <-b.quit:
+				return
+			}
+
+			if !ok {
+				return
+			}
+		}
+	}()
+
+	return nil
+}
+
+func (b *browser) browseServices(serviceType, domain string) error {
+	key := typeKey{serviceType, domain}
+	if b.browsing[key] {
+		return nil
+	}
+
+	sb, err := b.server.ServiceBrowserNew(avahi.InterfaceUnspec, avahi.ProtoUnspec, serviceType, domain, 0)
+	if err != nil {
+		return err
+	}
+
+	b.browsing[key] = true
+	b.pending++
+
+	go func() {
+		defer b.server.ServiceBrowserFree(sb)
+
+		done := false
+
+		for {
+			var ok bool
+
+			select {
+			case s := <-sb.AddChannel:
+				ok = b.send(message{kind: messageNew, service: s})
+			case s := <-sb.RemoveChannel:
+				ok = b.send(message{kind: messageRemove, service: s})
+			case <-sb.AllForNowChannel:
+				ok = b.finished(message{kind: messageAllForNow}, &done)
+			case <-sb.CacheExhaustedChannel:
+				ok = b.finished(message{kind: messageCacheExhausted}, &done)
+			case err := <-sb.FailureChannel:
+				ok = b.finished(message{kind: messageFailure, err: err}, &done)
+			case <-b.quit:
+				return
+			}
+
+			if !ok {
+				return
+			}
+		}
+	}()
+
+	return nil
+}
+
+func (b *browser) resolve(ctx context.Context, s avahi.Service) {
+	b.pending++
+
+	go func() {
+		r, err := b.server.ResolveServiceContext(ctx, s.Interface, s.Protocol, s.Name, s.Type, s.Domain, avahi.ProtoUnspec, 0)
+		if err != nil {
+			b.send(message{kind: messageResolveFailure, service: s, err: err})
+			return
+		}
+
+		b.send(message{kind: messageResolved, service: r})
+	}()
+}
+
+// run handles all events until browsing is complete or ctx ends
+func (b *browser) run(ctx context.Context) error {
+	defer close(b.quit)
+
+	for {
+		if b.pending == 0 && (b.cfg.terminate || b.cfg.cache) {
+			return b.out.finish()
+		}
+
+		var m message
+
+		select {
+		case m = <-b.messages:
+		case <-ctx.Done():
+			return b.out.finish()
+		}
+
+		switch m.kind {
+		case messageNewType:
+			err := b.browseServices(m.serviceType.Type, m.serviceType.Domain)
+			if err != nil {
+				return fmt.Errorf("Failed to create service browser for %s: %v", m.serviceType.Type, err)
+			}
+
+		case messageNew:
+			if b.cfg.ignoreLocal && m.service.Flags&avahi.LookupResultLocal != 0 {
+				continue
+			}
+
+			b.out.event(b.record(eventNew, m.service))
+
+			if b.cfg.resolve {
+				b.resolve(ctx, m.service)
+			}
+
+		case messageRemove:
+			if b.cfg.ignoreLocal && m.service.Flags&avahi.LookupResultLocal != 0 {
+				continue
+			}
+
+			b.out.event(b.record(eventRemove, m.service))
+
+		case messageResolved:
+			b.pending--
+			b.out.event(b.record(eventResolved, m.service))
+
+		case messageResolveFailure:
+			b.pending--
+
+			r := b.record(eventResolveFailure, m.service)
+			r.Error = m.err.Error()
+			b.out.event(r)
+
+		case messageAllForNow, messageCacheExhausted:
+			b.pending--
+
+			if m.kind == messageAllForNow {
+				b.out.event(record{Event: eventAllForNow})
+			} else {
+				b.out.event(record{Event: eventCacheExhausted})
+			}
+
+		case messageFailure:
+			b.pending--
+			b.out.event(record{Event: eventFailure, Error: m.err.Error()})
+		}
+	}
+}
+
+// record converts s for output
+func (b *browser) record(event string, s avahi.Service) record {
+	r := record{
+		Event:     event,
+		Interface: b.interfaceName(s.Interface),
+		Protocol:  protocolName(s.Protocol),
+		Name:      s.Name,
+		Type:      s.Type,
+		Domain:    s.Domain,
+	}
+
+	if event == eventResolved {
+		r.Host = s.Host
+		r.Address = s.Address
+		r.Port = s.Port
+
+		for _, t := range s.Txt {
+			r.Txt = append(r.Txt, string(t))
+		}
+	}
+
+	return r
+}
+
+func (b *browser) interfaceName(index int32) string {
+	if index == avahi.InterfaceUnspec {
+		return "n/a"
+	}
+
+	if name, ok := b.interfaces[index]; ok {
+		return name
+	}
+
+	name, err := b.server.GetNetworkInterfaceNameByIndex(index)
+	if err != nil {
+		name = strconv.Itoa(int(index))
+	}
+
+	b.interfaces[index] = name
+
+	return name
+}
+
+func protocolName(protocol int32) string {
+	switch protocol {
+	case avahi.ProtoInet:
+		return "IPv4"
+	case avahi.ProtoInet6:
+		return "IPv6"
+	}
+
+	return "n/a"
+}
@@ -0,0 +1,125 @@
+// Command avahi-browse browses for DNS-SD services like the avahi-browse tool
+// that ships with Avahi, and can also write its results as JSON or NDJSON.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	dbus "github.com/godbus/dbus/v5"
+	"github.com/holoplot/go-avahi"
+)
+
+type config struct {
+	all         bool
+	resolve     bool
+	terminate   bool
+	cache       bool
+	parsable    bool
+	ignoreLocal bool
+	verbose     bool
+	domain      string
+	output      string
+}
+
+func parseFlags(args []string) (config, []string, error) {
+	var cfg config
+
+	fs := flag.NewFlagSet("avahi-browse", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: avahi-browse [options] <service type>\n       avahi-browse [options] -a\n\n")
+		fs.PrintDefaults()
+	}
+
+	boolFlag := func(p *bool, short, long, usage string) {
+		fs.BoolVar(p, short, false, usage)
+		fs.BoolVar(p, long, false, usage)
+	}
+
+	stringFlag := func(p *string, short, long, value, usage string) {
+		fs.StringVar(p, short, value, usage)
+		fs.StringVar(p, long, value, usage)
+	}
+
+	boolFlag(&cfg.all, "a", "all", "Show all services, regardless of the type")
+	boolFlag(&cfg.resolve, "r", "resolve", "Resolve services found")
+	boolFlag(&cfg.terminate, "t", "terminate", "Terminate after dumping a more or less complete list")
+	boolFlag(&cfg.cache, "c", "cache", "Terminate after dumping all entries from the cache")
+	boolFlag(&cfg.parsable, "p", "parsable", "Output in parsable format")
+	boolFlag(&cfg.ignoreLocal, "l", "ignore-local", "Ignore local services")
+	boolFlag(&cfg.verbose, "v", "verbose", "Enable verbose mode")
+	stringFlag(&cfg.domain, "d", "domain", "", "The domain to browse in")
+	stringFlag(&cfg.output, "o", "output", "text", "Output format: text, json or ndjson")
+
+	if err := fs.Parse(args); err != nil {
+		return cfg, nil, err
+	}
+
+	switch cfg.output {
+	case "text", "json", "ndjson":
+	default:
+		return cfg, nil, fmt.Errorf("invalid output format %q", cfg.output)
+	}
+
+	if cfg.all == (fs.NArg() == 1) || fs.NArg() > 1 {
+		fs.Usage()
+		return cfg, nil, flag.ErrHelp
+	}
+
+	return cfg, fs.Args(), nil
+}
+
+func main() {
+	cfg, args, err := parseFlags(os.Args[1:])
+	if err == flag.ErrHelp {
+		os.Exit(2)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	conn, err := dbus.SystemBus()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to connect to the system bus: %v\n", err)
+		os.Exit(1)
+	}
+
+	server, err := avahi.ServerNew(conn)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to create client object: %v\n", err)
+		os.Exit(1)
+	}
+	defer server.Close()
+
+	if cfg.verbose {
+		version, _ := server.GetVersionString()
+		host, _ := server.GetHostNameFqdn()
+		fmt.Fprintf(os.Stderr, "Server version: %s; Host name: %s\n", version, host)
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	b := newBrowser(server, cfg, newOutput(cfg, os.Stdout, os.Stderr))
+
+	if cfg.all {
+		err = b.browseServiceTypes(cfg.domain)
+	} else {
+		err = b.browseServices(args[0], cfg.domain)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to create browser: %v\n", err)
+		os.Exit(1)
+	}
+
+	err = b.run(ctx)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
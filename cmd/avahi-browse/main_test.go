@@ -0,0 +1,102 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/holoplot/go-avahi"
+	"github.com/holoplot/go-avahi/avahitest"
+)
+
+func TestParseFlags(t *testing.T) {
+	cfg, args, err := parseFlags([]string{"-r", "--terminate", "-o", "ndjson", "_ipp._tcp"})
+	if err != nil || !cfg.resolve || !cfg.terminate || cfg.output != "ndjson" || len(args) != 1 {
+		t.Fatalf("parseFlags() returned %+v, %v, %v", cfg, args, err)
+	}
+
+	if _, _, err := parseFlags([]string{"-a", "_ipp._tcp"}); err == nil {
+		t.Fatal("parseFlags() accepted -a together with a service type")
+	}
+
+	if _, _, err := parseFlags([]string{"-o", "xml", "-a"}); err == nil {
+		t.Fatal("parseFlags() accepted an invalid output format")
+	}
+}
+
+func TestTextOutput(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+
+	resolved := record{
+		Event:     eventResolved,
+		Interface: "eth0",
+		Protocol:  "IPv4",
+		Name:      "Office Printer",
+		Type:      "_ipp._tcp",
+		Domain:    "local",
+		Host:      "printer.local",
+		Address:   "192.168.1.20",
+		Port:      631,
+		Txt:       []string{"txtvers=1", "rp=ipp"},
+	}
+
+	o := newOutput(config{output: "text", parsable: true}, &stdout, &stderr)
+	o.event(resolved)
+
+	expected := `=;eth0;IPv4;Office\032Printer;_ipp._tcp;local;printer.local;192.168.1.20;631;"txtvers=1" "rp=ipp"` + "\n"
+	if stdout.String() != expected {
+		t.Fatalf("parsable output is %q", stdout.String())
+	}
+
+	stdout.Reset()
+
+	o = newOutput(config{output: "text"}, &stdout, &stderr)
+	o.event(resolved)
+
+	expected = "=   eth0 IPv4 Office Printer                           _ipp._tcp            local\n" +
+		"   hostname = [printer.local]\n   address = [192.168.1.20]\n   port = [631]\n   txt = [\"txtvers=1\" \"rp=ipp\"]\n"
+	if stdout.String() != expected {
+		t.Fatalf("text output is %q", stdout.String())
+	}
+}
+
+func TestBrowse(t *testing.T) {
+	d, server := avahitest.NewServer(t)
+
+	for _, s := range []avahi.Service{
+		{Interface: 2, Protocol: avahi.ProtoInet, Name: "Printer", Type: "_ipp._tcp", Domain: "local",
+			Host: "printer.local", Aprotocol: avahi.ProtoInet, Address: "192.168.1.20", Port: 631},
+		{Interface: 2, Protocol: avahi.ProtoInet, Name: "Web", Type: "_http._tcp", Domain: "local",
+			Host: "web.local", Aprotocol: avahi.ProtoInet, Address: "192.168.1.30", Port: 80},
+	} {
+		d.AddService(s)
+	}
+
+	var stdout, stderr bytes.Buffer
+
+	cfg := config{all: true, resolve: true, terminate: true, output: "json"}
+	b := newBrowser(server, cfg, newOutput(cfg, &stdout, &stderr))
+
+	if err := b.browseServiceTypes(""); err != nil {
+		t.Fatalf("browseServiceTypes() failed: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err := b.run(ctx); err != nil {
+		t.Fatalf("run() failed: %v", err)
+	}
+
+	var services []record
+	if err := json.Unmarshal(stdout.Bytes(), &services); err != nil {
+		t.Fatalf("output is no JSON array: %v\n%s", err, stdout.String())
+	}
+
+	if len(services) != 2 || services[0].Name != "Web" || services[0].Address != "192.168.1.30" ||
+		services[1].Name != "Printer" || services[1].Port != 631 || services[1].Interface != "eth0" {
+		t.Fatalf("output is %s", stdout.String())
+	}
+}
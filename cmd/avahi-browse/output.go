@@ -0,0 +1,201 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/holoplot/go-avahi"
+)
+
+const (
+	eventNew            = "new"
+	eventRemove         = "remove"
+	eventResolved       = "resolved"
+	eventResolveFailure = "resolve-failure"
+	eventAllForNow      = "all-for-now"
+	eventCacheExhausted = "cache-exhausted"
+	eventFailure        = "failure"
+)
+
+// A record is a single line of output, and an object in JSON output
+type record struct {
+	Event     string   `json:"event,omitempty"`
+	Interface string   `json:"interface,omitempty"`
+	Protocol  string   `json:"protocol,omitempty"`
+	Name      string   `json:"name,omitempty"`
+	Type      string   `json:"type,omitempty"`
+	Domain    string   `json:"domain,omitempty"`
+	Host      string   `json:"host,omitempty"`
+	Address   string   `json:"address,omitempty"`
+	Port      uint16   `json:"port,omitempty"`
+	Txt       []string `json:"txt,omitempty"`
+	Error     string   `json:"error,omitempty"`
+}
+
+// An output writes records as they happen, or all at once in finish
+type output interface {
+	event(r record)
+	finish() error
+}
+
+func newOutput(cfg config, stdout, stderr io.Writer) output {
+	switch cfg.output {
+	case "json":
+		return &jsonOutput{w: stdout, services: make(map[serviceKey]record)}
+	case "ndjson":
+		return &ndjsonOutput{encoder: json.NewEncoder(stdout)}
+	}
+
+	return &textOutput{w: stdout, stderr: stderr, parsable: cfg.parsable, verbose: cfg.verbose}
+}
+
+// textOutput writes the format of avahi-browse
+type textOutput struct {
+	w        io.Writer
+	stderr   io.Writer
+	parsable bool
+	verbose  bool
+}
+
+func (o *textOutput) event(r record) {
+	switch r.Event {
+	case eventNew:
+		o.serviceLine('+', r)
+	case eventRemove:
+		o.serviceLine('-', r)
+	case eventResolved:
+		o.serviceLine('=', r)
+
+		if o.parsable {
+			fmt.Fprintf(o.w, ";%s;%s;%d;%s\n", r.Host, r.Address, r.Port, quoteTxt(r.Txt))
+		} else {
+			fmt.Fprintf(o.w, "   hostname = [%s]\n   address = [%s]\n   port = [%d]\n   txt = [%s]\n",
+				r.Host, r.Address, r.Port, quoteTxt(r.Txt))
+		}
+	case eventResolveFailure:
+		fmt.Fprintf(o.stderr, "Failed to resolve service '%s' of type '%s' in domain '%s': %s\n", r.Name, r.Type, r.Domain, r.Error)
+	case eventFailure:
+		fmt.Fprintf(o.stderr, "Failure: %s\n", r.Error)
+	case eventAllForNow:
+		if o.verbose {
+			fmt.Fprintln(o.stderr, ": All for now")
+		}
+	case eventCacheExhausted:
+		if o.verbose {
+			fmt.Fprintln(o.stderr, ": Cache exhausted")
+		}
+	}
+}
+
+// serviceLine writes the first line for a service. In parsable mode, the line
+// of a resolved service is continued by the caller.
+func (o *textOutput) serviceLine(c byte, r record) {
+	if o.parsable {
+		fmt.Fprintf(o.w, "%c;%s;%s;%s;%s;%s", c, r.Interface, r.Protocol, avahi.EscapeLabel(r.Name), r.Type, r.Domain)
+		if r.Event != eventResolved {
+			fmt.Fprintln(o.w)
+		}
+		return
+	}
+
+	fmt.Fprintf(o.w, "%c %6s %4s %-40s %-20s %s\n", c, r.Interface, r.Protocol, printable(r.Name), r.Type, r.Domain)
+}
+
+func (o *textOutput) finish() error {
+	return nil
+}
+
+// quoteTxt formats TXT strings like avahi_string_list_to_string
+func quoteTxt(txt []string) string {
+	quoted := make([]string, len(txt))
+	for i, t := range txt {
+		quoted[i] = `"` + t + `"`
+	}
+
+	return strings.Join(quoted, " ")
+}
+
+// printable replaces control characters, like avahi-browse does for non-parsable output
+func printable(name string) string {
+	return strings.Map(func(r rune) rune {
+		if r < 0x20 || r == 0x7f {
+			return '_'
+		}
+		return r
+	}, name)
+}
+
+// ndjsonOutput writes every record as a line of JSON
+type ndjsonOutput struct {
+	encoder *json.Encoder
+}
+
+func (o *ndjsonOutput) event(r record) {
+	o.encoder.Encode(r)
+}
+
+func (o *ndjsonOutput) finish() error {
+	return nil
+}
+
+type serviceKey struct {
+	iface, protocol, name, serviceType, domain string
+}
+
+// jsonOutput writes the services present at the end as a JSON array
+type jsonOutput struct {
+	w        io.Writer
+	services map[serviceKey]record
+}
+
+func (o *jsonOutput) event(r record) {
+	key := serviceKey{r.Interface, r.Protocol, r.Name, r.Type, r.Domain}
+
+	switch r.Event {
+	case eventNew:
+		if _, ok := o.services[key]; !ok {
+			r.Event = ""
+			o.services[key] = r
+		}
+	case eventResolved:
+		if _, ok := o.services[key]; ok {
+			r.Event = ""
+			o.services[key] = r
+		}
+	case eventRemove:
+		delete(o.services, key)
+	}
+}
+
+func (o *jsonOutput) finish() error {
+	services := make([]record, 0, len(o.services))
+	for _, r := range o.services {
+		services = append(services, r)
+	}
+
+	sort.Slice(services, func(i, j int) bool {
+		a, b := services[i], services[j]
+		if a.Type != b.Type {
+			return a.Type < b.Type
+		}
+		if a.Name != b.Name {
+			return a.Name < b.Name
+		}
+		if a.Interface != b.Interface {
+			return a.Interface < b.Interface
+		}
+		return a.Protocol < b.Protocol
+	})
+
+	data, err := json.MarshalIndent(services, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	_, err = o.w.Write(append(data, '\n'))
+
+	return err
+}
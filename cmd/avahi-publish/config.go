@@ -0,0 +1,256 @@
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/holoplot/go-avahi"
+)
+
+// A fileConfig is the content of a JSON config file
+type fileConfig struct {
+	Services  []serviceConfig `json:"services"`
+	Addresses []addressConfig `json:"addresses"`
+	Records   []recordConfig  `json:"records"`
+}
+
+type serviceConfig struct {
+	Interface string   `json:"interface"`
+	Protocol  string   `json:"protocol"`
+	Name      string   `json:"name"`
+	Type      string   `json:"type"`
+	Domain    string   `json:"domain"`
+	Host      string   `json:"host"`
+	Port      uint16   `json:"port"`
+	Subtypes  []string `json:"subtypes"`
+	Txt       []string `json:"txt"`
+}
+
+type addressConfig struct {
+	Interface string `json:"interface"`
+	Protocol  string `json:"protocol"`
+	Name      string `json:"name"`
+	Address   string `json:"address"`
+	NoReverse bool   `json:"no-reverse"`
+}
+
+// A recordConfig declares a raw record. The data is given either as hex in
+// Data, as strings in Txt for TXT records, or in Value as the address of A and
+// AAAA records or the name of PTR and CNAME records.
+type recordConfig struct {
+	Interface string   `json:"interface"`
+	Protocol  string   `json:"protocol"`
+	Name      string   `json:"name"`
+	Type      string   `json:"type"`
+	Class     uint16   `json:"class"`
+	TTL       uint32   `json:"ttl"`
+	Value     string   `json:"value"`
+	Txt       []string `json:"txt"`
+	Data      string   `json:"data"`
+}
+
+// An interfaceResolver looks up interface indexes by name, like Server.GetNetworkInterfaceIndexByName
+type interfaceResolver func(name string) (int32, error)
+
+// loadConfig reads a JSON config file
+func loadConfig(path string) (fileConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fileConfig{}, err
+	}
+
+	return parseConfig(data)
+}
+
+// parseConfig parses JSON and rejects unknown fields
+func parseConfig(data []byte) (fileConfig, error) {
+	var cfg fileConfig
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+
+	err := decoder.Decode(&cfg)
+	if err != nil {
+		return fileConfig{}, err
+	}
+
+	return cfg, nil
+}
+
+// spec converts the config to an EntryGroupSpec
+func (cfg fileConfig) spec(interfaces interfaceResolver) (avahi.EntryGroupSpec, error) {
+	var spec avahi.EntryGroupSpec
+
+	for _, s := range cfg.Services {
+		iface, protocol, err := location(interfaces, s.Interface, s.Protocol)
+		if err != nil {
+			return spec, fmt.Errorf("service %q: %w", s.Name, err)
+		}
+
+		spec.Services = append(spec.Services, avahi.ServiceSpec{
+			Interface: iface,
+			Protocol:  protocol,
+			Name:      s.Name,
+			Type:      s.Type,
+			Domain:    s.Domain,
+			Host:      s.Host,
+			Port:      s.Port,
+			Txt:       txtOf(s.Txt),
+			Subtypes:  s.Subtypes,
+		})
+	}
+
+	for _, a := range cfg.Addresses {
+		iface, protocol, err := location(interfaces, a.Interface, a.Protocol)
+		if err != nil {
+			return spec, fmt.Errorf("address %q: %w", a.Name, err)
+		}
+
+		var flags uint32
+		if a.NoReverse {
+			flags = avahi.PublishNoReverse
+		}
+
+		spec.Addresses = append(spec.Addresses, avahi.AddressSpec{
+			Interface: iface,
+			Protocol:  protocol,
+			Flags:     flags,
+			Name:      a.Name,
+			Address:   a.Address,
+		})
+	}
+
+	for _, r := range cfg.Records {
+		record, err := r.spec(interfaces)
+		if err != nil {
+			return spec, fmt.Errorf("record %q: %w", r.Name, err)
+		}
+
+		spec.Records = append(spec.Records, record)
+	}
+
+	return spec, nil
+}
+
+var recordTypes = map[string]uint16{
+	"A":     avahi.DNSTypeA,
+	"NS":    avahi.DNSTypeNS,
+	"CNAME": avahi.DNSTypeCNAME,
+	"PTR":   avahi.DNSTypePTR,
+	"HINFO": avahi.DNSTypeHINFO,
+	"MX":    avahi.DNSTypeMX,
+	"TXT":   avahi.DNSTypeTXT,
+	"AAAA":  avahi.DNSTypeAAAA,
+	"SRV":   avahi.DNSTypeSRV,
+}
+
+func (r recordConfig) spec(interfaces interfaceResolver) (avahi.RecordSpec, error) {
+	iface, protocol, err := location(interfaces, r.Interface, r.Protocol)
+	if err != nil {
+		return avahi.RecordSpec{}, err
+	}
+
+	recordType, ok := recordTypes[strings.ToUpper(r.Type)]
+	if !ok {
+		n, err := strconv.ParseUint(r.Type, 10, 16)
+		if err != nil {
+			return avahi.RecordSpec{}, fmt.Errorf("unknown record type %q", r.Type)
+		}
+
+		recordType = uint16(n)
+	}
+
+	spec := avahi.RecordSpec{
+		Interface: iface,
+		Protocol:  protocol,
+		Name:      r.Name,
+		Class:     r.Class,
+		Type:      recordType,
+		TTL:       r.TTL,
+	}
+
+	if spec.Class == 0 {
+		spec.Class = avahi.DNSClassIN
+	}
+
+	if spec.TTL == 0 {
+		spec.TTL = 120
+	}
+
+	var rdata avahi.Rdata
+
+	switch {
+	case r.Data != "":
+		spec.Rdata, err = hex.DecodeString(r.Data)
+		return spec, err
+
+	case recordType == avahi.DNSTypeTXT:
+		rdata = avahi.RdataTXT{Txt: txtOf(r.Txt)}
+
+	case recordType == avahi.DNSTypeA || recordType == avahi.DNSTypeAAAA:
+		ip := net.ParseIP(r.Value)
+		if ip == nil {
+			return spec, fmt.Errorf("invalid address %q", r.Value)
+		}
+
+		if recordType == avahi.DNSTypeA {
+			rdata = avahi.RdataA{Address: ip}
+		} else {
+			rdata = avahi.RdataAAAA{Address: ip}
+		}
+
+	case recordType == avahi.DNSTypePTR:
+		rdata = avahi.RdataPTR{Name: r.Value}
+
+	case recordType == avahi.DNSTypeCNAME:
+		rdata = avahi.RdataCNAME{Name: r.Value}
+
+	default:
+		return spec, fmt.Errorf("records of type %s need hex data", r.Type)
+	}
+
+	spec.Rdata, err = rdata.Encode()
+
+	return spec, err
+}
+
+// location converts an interface name and a protocol name to their values for the daemon
+func location(interfaces interfaceResolver, ifaceName, protocolName string) (int32, int32, error) {
+	iface := int32(avahi.InterfaceUnspec)
+
+	if ifaceName != "" {
+		var err error
+
+		iface, err = interfaces(ifaceName)
+		if err != nil {
+			return 0, 0, fmt.Errorf("interface %s: %w", ifaceName, err)
+		}
+	}
+
+	switch strings.ToLower(protocolName) {
+	case "", "any":
+		return iface, avahi.ProtoUnspec, nil
+	case "ipv4":
+		return iface, avahi.ProtoInet, nil
+	case "ipv6":
+		return iface, avahi.ProtoInet6, nil
+	}
+
+	return 0, 0, fmt.Errorf("invalid protocol %q", protocolName)
+}
+
+func txtOf(values []string) [][]byte {
+	var txt [][]byte
+
+	for _, s := range values {
+		txt = append(txt, []byte(s))
+	}
+
+	return txt
+}
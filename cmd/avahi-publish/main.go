@@ -0,0 +1,256 @@
+// Command avahi-publish publishes services, addresses and records like the
+// avahi-publish tool that ships with Avahi. Besides the command line, entries
+// can be read from a JSON file, which is reloaded on SIGHUP.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+
+	dbus "github.com/godbus/dbus/v5"
+	"github.com/holoplot/go-avahi"
+)
+
+// stringList collects the values of a repeated flag
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *stringList) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
+
+type config struct {
+	service   bool
+	address   bool
+	file      string
+	subtypes  stringList
+	domain    string
+	host      string
+	noReverse bool
+	args      []string
+}
+
+func parseFlags(args []string) (config, error) {
+	var cfg config
+
+	fs := flag.NewFlagSet("avahi-publish", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: avahi-publish -s [options] <name> <type> <port> [<txt ...>]\n"+
+			"       avahi-publish -a [options] <host-name> <address>\n"+
+			"       avahi-publish -f <file> [options]\n\n")
+		fs.PrintDefaults()
+	}
+
+	boolFlag := func(p *bool, short, long, usage string) {
+		fs.BoolVar(p, short, false, usage)
+		fs.BoolVar(p, long, false, usage)
+	}
+
+	stringFlag := func(p *string, short, long, usage string) {
+		fs.StringVar(p, short, "", usage)
+		fs.StringVar(p, long, "", usage)
+	}
+
+	boolFlag(&cfg.service, "s", "service", "Publish service")
+	boolFlag(&cfg.address, "a", "address", "Publish address")
+	boolFlag(&cfg.noReverse, "R", "no-reverse", "Do not publish reverse entry with address")
+	stringFlag(&cfg.file, "f", "file", "Publish the entries of a JSON file, reloaded on SIGHUP")
+	stringFlag(&cfg.domain, "d", "domain", "Domain to publish service in")
+	stringFlag(&cfg.host, "H", "host", "Host where service resides")
+	fs.Var(&cfg.subtypes, "subtype", "An additional subtype to register this service with")
+
+	if err := fs.Parse(args); err != nil {
+		return cfg, err
+	}
+
+	cfg.args = fs.Args()
+
+	switch {
+	case cfg.service && cfg.address:
+		return cfg, fmt.Errorf("-s and -a are mutually exclusive")
+	case cfg.service && len(cfg.args) < 3:
+		return cfg, fmt.Errorf("-s needs a name, a type and a port")
+	case cfg.address && len(cfg.args) != 2:
+		return cfg, fmt.Errorf("-a needs a host name and an address")
+	case !cfg.service && !cfg.address && (cfg.file == "" || len(cfg.args) > 0):
+		fs.Usage()
+		return cfg, flag.ErrHelp
+	}
+
+	return cfg, nil
+}
+
+// spec returns the entries declared on the command line and in the file
+func (cfg config) spec(interfaces interfaceResolver) (avahi.EntryGroupSpec, error) {
+	var spec avahi.EntryGroupSpec
+
+	if cfg.file != "" {
+		fc, err := loadConfig(cfg.file)
+		if err != nil {
+			return spec, err
+		}
+
+		spec, err = fc.spec(interfaces)
+		if err != nil {
+			return spec, fmt.Errorf("%s: %w", cfg.file, err)
+		}
+	}
+
+	switch {
+	case cfg.service:
+		port, err := strconv.ParseUint(cfg.args[2], 10, 16)
+		if err != nil {
+			return spec, fmt.Errorf("invalid port %q", cfg.args[2])
+		}
+
+		spec.Services = append(spec.Services, avahi.ServiceSpec{
+			Interface: avahi.InterfaceUnspec,
+			Protocol:  avahi.ProtoUnspec,
+			Name:      cfg.args[0],
+			Type:      cfg.args[1],
+			Domain:    cfg.domain,
+			Host:      cfg.host,
+			Port:      uint16(port),
+			Txt:       txtOf(cfg.args[3:]),
+			Subtypes:  cfg.subtypes,
+		})
+
+	case cfg.address:
+		var flags uint32
+		if cfg.noReverse {
+			flags = avahi.PublishNoReverse
+		}
+
+		spec.Addresses = append(spec.Addresses, avahi.AddressSpec{
+			Interface: avahi.InterfaceUnspec,
+			Protocol:  avahi.ProtoUnspec,
+			Flags:     flags,
+			Name:      cfg.args[0],
+			Address:   cfg.args[1],
+		})
+	}
+
+	return spec, spec.Validate()
+}
+
+func main() {
+	logger := log.New(os.Stderr, "", 0)
+
+	cfg, err := parseFlags(os.Args[1:])
+	if err == flag.ErrHelp {
+		os.Exit(2)
+	}
+	if err != nil {
+		logger.Fatal(err)
+	}
+
+	conn, err := dbus.SystemBus()
+	if err != nil {
+		logger.Fatalf("Failed to connect to the system bus: %v", err)
+	}
+
+	server, err := avahi.ServerNew(conn)
+	if err != nil {
+		logger.Fatalf("Failed to create client object: %v", err)
+	}
+	defer server.Close()
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+
+	err = run(ctx, server, cfg, hup, logger)
+	if err != nil {
+		logger.Print(err)
+		os.Exit(1)
+	}
+}
+
+// run publishes the entries of cfg until ctx ends, and reloads them whenever reload receives a value
+func run(ctx context.Context, server *avahi.Server, cfg config, reload chan os.Signal, logger *log.Logger) error {
+	spec, err := cfg.spec(server.GetNetworkInterfaceIndexByName)
+	if err != nil {
+		return err
+	}
+
+	p, err := server.PublisherNewSpec(spec)
+	if err != nil {
+		return fmt.Errorf("Failed to register: %w", err)
+	}
+	defer server.PublisherFree(p)
+
+	for {
+		select {
+		case state := <-p.StateChangeChannel:
+			err = stateChanged(state, logger)
+			if err != nil {
+				return err
+			}
+
+		case <-reload:
+			if cfg.file == "" {
+				continue
+			}
+
+			// A broken file is reported, and the entries published so far are kept
+			spec, err := cfg.spec(server.GetNetworkInterfaceIndexByName)
+			if err == nil {
+				err = p.UpdateSpec(spec)
+			}
+
+			if err != nil {
+				logger.Printf("Failed to reload %s: %v", cfg.file, err)
+			} else {
+				logger.Printf("Reloaded %s", cfg.file)
+			}
+
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+// stateChanged logs the state of a service. The returned error ends the program.
+func stateChanged(state avahi.PublisherState, logger *log.Logger) error {
+	switch state.State {
+	case avahi.EntryGroupRegistering:
+		if state.Name == "" {
+			logger.Print("Registering")
+		} else {
+			logger.Printf("Registering '%s'", state.Name)
+		}
+
+	case avahi.EntryGroupEstablished:
+		if state.Name == "" {
+			logger.Print("Established")
+		} else {
+			logger.Printf("Established under name '%s'", state.Name)
+		}
+
+	case avahi.EntryGroupCollision:
+		if state.Alternative == "" {
+			return errors.New("Host name conflict")
+		}
+
+		logger.Printf("Service name collision, renaming '%s' to '%s'", state.Name, state.Alternative)
+
+	case avahi.EntryGroupFailure:
+		return fmt.Errorf("Failed to register: %v", state.Error)
+	}
+
+	return nil
+}
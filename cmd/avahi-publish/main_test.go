@@ -0,0 +1,146 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/holoplot/go-avahi"
+	"github.com/holoplot/go-avahi/avahitest"
+)
+
+func testInterfaces(name string) (int32, error) {
+	if name == "eth0" {
+		return 2, nil
+	}
+
+	return 0, avahi.ErrInvalidInterface
+}
+
+func TestParseConfig(t *testing.T) {
+	jsonConfig := `{
+  "services": [{"name": "Printer", "type": "_ipp._tcp", "port": 631, "interface": "eth0",
+    "protocol": "ipv4", "subtypes": ["_color"], "txt": ["txtvers=1"]}],
+  "addresses": [{"name": "printer.local", "address": "192.168.1.20", "no-reverse": true}],
+  "records": [{"name": "alias.local", "type": "CNAME", "value": "printer.local"}]
+}`
+
+	fc, err := parseConfig([]byte(jsonConfig))
+	if err != nil {
+		t.Fatalf("parseConfig() failed: %v", err)
+	}
+
+	spec, err := fc.spec(testInterfaces)
+	if err != nil {
+		t.Fatalf("spec() failed: %v", err)
+	}
+
+	s := spec.Services[0]
+	if s.Interface != 2 || s.Protocol != avahi.ProtoInet || s.Port != 631 || s.Subtypes[0] != "_color" ||
+		string(s.Txt[0]) != "txtvers=1" {
+		t.Fatalf("service is %+v", s)
+	}
+
+	if a := spec.Addresses[0]; a.Flags != avahi.PublishNoReverse || a.Interface != avahi.InterfaceUnspec {
+		t.Fatalf("address is %+v", a)
+	}
+
+	r := spec.Records[0]
+	if r.Type != avahi.DNSTypeCNAME || r.Class != avahi.DNSClassIN || r.TTL != 120 {
+		t.Fatalf("record is %+v", r)
+	}
+
+	if data, err := (avahi.RdataCNAME{Name: "printer.local"}).Encode(); err != nil || !bytes.Equal(r.Rdata, data) {
+		t.Fatalf("record data is %x", r.Rdata)
+	}
+
+	if _, err := parseConfig([]byte(`{"services": [{"nmae": "Printer"}]}`)); err == nil {
+		t.Fatal("parseConfig() accepted an unknown field")
+	}
+
+	fc, _ = parseConfig([]byte(`{"services": [{"name": "Printer", "type": "_ipp._tcp", "interface": "wlan9"}]}`))
+	if _, err := fc.spec(testInterfaces); !errors.Is(err, avahi.ErrInvalidInterface) {
+		t.Fatalf("spec() for an unknown interface returned %v", err)
+	}
+}
+
+// syncBuffer hands the lines written by a logger to the test
+type syncBuffer struct {
+	lines chan string
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.lines <- strings.TrimSpace(string(p))
+	return len(p), nil
+}
+
+func (b *syncBuffer) expect(t *testing.T, line string) {
+	t.Helper()
+
+	timeout := time.After(5 * time.Second)
+
+	for {
+		select {
+		case l := <-b.lines:
+			if l == line {
+				return
+			}
+		case <-timeout:
+			t.Fatalf("no log line %q", line)
+		}
+	}
+}
+
+func TestRun(t *testing.T) {
+	d, server := avahitest.NewServer(t)
+
+	file := filepath.Join(t.TempDir(), "services.json")
+
+	write := func(txt string) {
+		data := `{"services": [{"name": "Printer", "type": "_ipp._tcp", "port": 631, "txt": ["` + txt + `"]}]}`
+		if err := os.WriteFile(file, []byte(data), 0o644); err != nil {
+			t.Fatalf("WriteFile() failed: %v", err)
+		}
+	}
+
+	write("v=1")
+	d.AddCollision("Printer")
+
+	cfg, err := parseFlags([]string{"-f", file})
+	if err != nil {
+		t.Fatalf("parseFlags() failed: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	reload := make(chan os.Signal, 1)
+	logs := &syncBuffer{lines: make(chan string, 100)}
+
+	done := make(chan error)
+	go func() {
+		done <- run(ctx, server, cfg, reload, log.New(logs, "", 0))
+	}()
+
+	logs.expect(t, "Service name collision, renaming 'Printer' to 'Printer #2'")
+	logs.expect(t, "Established under name 'Printer #2'")
+
+	write("v=2")
+	reload <- os.Interrupt
+	logs.expect(t, "Reloaded "+file)
+
+	services := d.Services()
+	if len(services) != 1 || services[0].Name != "Printer #2" || string(services[0].Txt[0]) != "v=2" {
+		t.Fatalf("published services are %+v", services)
+	}
+
+	cancel()
+
+	if err := <-done; err != nil {
+		t.Fatalf("run() failed: %v", err)
+	}
+}
@@ -0,0 +1,82 @@
+package avahi_test
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+
+	"github.com/holoplot/go-avahi"
+	"github.com/holoplot/go-avahi/avahitest"
+)
+
+func TestDialer(t *testing.T) {
+	d, s := avahitest.NewServer(t)
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "hello")
+	}))
+	t.Cleanup(ts.Close)
+
+	addrPort := netip.MustParseAddrPort(ts.Listener.Addr().String())
+
+	d.AddHost(avahi.HostName{
+		Interface: 1,
+		Protocol:  avahi.ProtoInet,
+		Name:      "web.local",
+		Aprotocol: avahi.ProtoInet,
+		Address:   addrPort.Addr().String(),
+		Flags:     avahi.LookupResultLocal,
+	})
+
+	d.AddService(avahi.Service{
+		Interface: 1,
+		Protocol:  avahi.ProtoInet,
+		Name:      "Web",
+		Type:      "_http._tcp",
+		Domain:    "local",
+		Host:      "web.local",
+		Aprotocol: avahi.ProtoInet,
+		Address:   addrPort.Addr().String(),
+		Port:      addrPort.Port(),
+		Flags:     avahi.LookupResultLocal,
+	})
+
+	dialer := &avahi.Dialer{Server: s}
+	client := &http.Client{Transport: &http.Transport{DialContext: dialer.DialContext}}
+
+	for _, host := range []string{
+		fmt.Sprintf("web.local:%d", addrPort.Port()),
+		fmt.Sprintf("Web._http._tcp.local:%d", addrPort.Port()),
+	} {
+		resp, err := client.Get("http://" + host + "/")
+		if err != nil {
+			t.Fatalf("Get() for %s failed: %v", host, err)
+		}
+
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil || string(body) != "hello" {
+			t.Fatalf("Get() for %s returned %q, %v", host, body, err)
+		}
+	}
+
+	conn, err := dialer.Dial("tcp", "Web._http._tcp.local")
+	if err != nil {
+		t.Fatalf("Dial() without port failed: %v", err)
+	}
+	if conn.RemoteAddr().String() != addrPort.String() {
+		t.Fatalf("Dial() connected to %s", conn.RemoteAddr())
+	}
+	conn.Close()
+
+	_, err = dialer.Dial("tcp6", fmt.Sprintf("web.local:%d", addrPort.Port()))
+	var opError *net.OpError
+	if !errors.As(err, &opError) || !errors.Is(err, avahi.ErrTimeout) {
+		t.Fatalf("Dial() over IPv6 returned %v", err)
+	}
+}
@@ -0,0 +1,298 @@
+package avahi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// A Dialer connects to .local host names and to DNS-SD service instances,
+// like "Printer._ipp._tcp.local", which it resolves through avahi-daemon.
+// All other addresses are passed to the underlying net.Dialer unchanged.
+// DialContext can be used as http.Transport.DialContext.
+type Dialer struct {
+	// Server is used to resolve names
+	Server *Server
+
+	// Dialer connects to the resolved addresses. If nil, a zero net.Dialer is used.
+	Dialer *net.Dialer
+
+	// FallbackDelay is the time to wait for a connection attempt before the
+	// next address is tried in parallel. If zero, 300ms are used.
+	FallbackDelay time.Duration
+
+	// ResolutionDelay is the time to wait for the address of the second protocol
+	// once the address of one protocol is known. If zero, 50ms are used.
+	ResolutionDelay time.Duration
+}
+
+// Dial connects to the address on the named network, see DialContext
+func (d *Dialer) Dial(network, address string) (net.Conn, error) {
+	return d.DialContext(context.Background(), network, address)
+}
+
+// DialContext connects to the address on the named network.
+// Addresses are either host:port for .local host names, or the name of a
+// service instance with an optional port, which defaults to the port of the
+// service. avahi-daemon reports a single address per name and protocol, so at most one
+// IPv6 and one IPv4 address are tried, the IPv6 one first, as in RFC 8305.
+func (d *Dialer) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	dialer := d.Dialer
+	if dialer == nil {
+		dialer = &net.Dialer{}
+	}
+
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		host, port = address, ""
+	}
+
+	var addrs []netip.AddrPort
+
+	if instance, serviceType, domain, ok := splitInstanceName(host); ok {
+		addrs, err = d.resolveService(ctx, network, instance, serviceType, domain, port)
+	} else if isLocalName(host) && port != "" {
+		addrs, err = d.resolveHostName(ctx, network, host, port)
+	} else {
+		return dialer.DialContext(ctx, network, address)
+	}
+
+	if err != nil {
+		return nil, &net.OpError{Op: "dial", Net: network, Err: err}
+	}
+
+	return d.dialParallel(ctx, dialer, network, addrs)
+}
+
+// isLocalName reports whether name is a host name in the .local domain
+func isLocalName(name string) bool {
+	name = strings.ToLower(strings.TrimSuffix(name, "."))
+	return strings.HasSuffix(name, ".local")
+}
+
+// splitInstanceName splits a service instance name like "Printer._ipp._tcp.local"
+func splitInstanceName(name string) (string, string, string, bool) {
+	instance, serviceType, domain, err := ServiceNameSplit(name)
+	if err != nil || instance == "" {
+		return "", "", "", false
+	}
+
+	return instance, serviceType, domain, true
+}
+
+// aprotocols returns the address protocols to resolve for network, preferred first
+func aprotocols(network string) []int32 {
+	switch {
+	case strings.HasSuffix(network, "4"):
+		return []int32{ProtoInet}
+	case strings.HasSuffix(network, "6"):
+		return []int32{ProtoInet6}
+	}
+
+	return []int32{ProtoInet6, ProtoInet}
+}
+
+// resolveEach calls resolve for all address protocols of network in parallel. Once one
+// succeeded, the others get ResolutionDelay more time. The addresses found are returned
+// in the order of aprotocols, without duplicates.
+func (d *Dialer) resolveEach(ctx context.Context, network string, resolve func(ctx context.Context, aprotocol int32) (netip.AddrPort, error)) ([]netip.AddrPort, error) {
+	protocols := aprotocols(network)
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	type result struct {
+		index int
+		addr  netip.AddrPort
+		err   error
+	}
+
+	results := make(chan result, len(protocols))
+
+	for i, aprotocol := range protocols {
+		go func(i int, aprotocol int32) {
+			addr, err := resolve(ctx, aprotocol)
+			results <- result{i, addr, err}
+		}(i, aprotocol)
+	}
+
+	delay := d.ResolutionDelay
+	if delay == 0 {
+		delay = 50 * time.Millisecond
+	}
+
+	found := make([]netip.AddrPort, len(protocols))
+	var firstErr error
+	var timeout <-chan time.Time
+
+	for pending := len(protocols); pending > 0; pending-- {
+		select {
+		case r := <-results:
+			if r.err != nil {
+				if firstErr == nil {
+					firstErr = r.err
+				}
+				continue
+			}
+
+			found[r.index] = r.addr
+
+			if timeout == nil {
+				timeout = time.After(delay)
+			}
+
+		case <-timeout:
+			pending = 0
+		}
+	}
+
+	var addrs []netip.AddrPort
+	seen := map[netip.AddrPort]bool{}
+
+	for _, addr := range found {
+		if addr.IsValid() && !seen[addr] {
+			seen[addr] = true
+			addrs = append(addrs, addr)
+		}
+	}
+
+	if len(addrs) == 0 {
+		if firstErr == nil {
+			firstErr = errors.New("no addresses found")
+		}
+
+		return nil, firstErr
+	}
+
+	return addrs, nil
+}
+
+func (d *Dialer) resolveHostName(ctx context.Context, network, name, port string) ([]netip.AddrPort, error) {
+	p, err := strconv.ParseUint(port, 10, 16)
+	if err != nil {
+		return nil, fmt.Errorf("invalid port %q", port)
+	}
+
+	return d.resolveEach(ctx, network, func(ctx context.Context, aprotocol int32) (netip.AddrPort, error) {
+		h, err := d.Server.ResolveHostNameContext(ctx, InterfaceUnspec, ProtoUnspec, name, aprotocol, 0)
+		if err != nil {
+			return netip.AddrPort{}, err
+		}
+
+		addr, err := h.Addr(d.Server)
+		if err != nil {
+			return netip.AddrPort{}, err
+		}
+
+		return netip.AddrPortFrom(addr, uint16(p)), nil
+	})
+}
+
+func (d *Dialer) resolveService(ctx context.Context, network, instance, serviceType, domain, port string) ([]netip.AddrPort, error) {
+	var p uint64
+
+	if port != "" {
+		var err error
+
+		p, err = strconv.ParseUint(port, 10, 16)
+		if err != nil {
+			return nil, fmt.Errorf("invalid port %q", port)
+		}
+	}
+
+	return d.resolveEach(ctx, network, func(ctx context.Context, aprotocol int32) (netip.AddrPort, error) {
+		s, err := d.Server.ResolveServiceContext(ctx, InterfaceUnspec, ProtoUnspec, instance, serviceType, domain, aprotocol, LookupNoTXT)
+		if err != nil {
+			return netip.AddrPort{}, err
+		}
+
+		if port != "" {
+			s.Port = uint16(p)
+		}
+
+		return s.AddrPort(d.Server)
+	})
+}
+
+// dialParallel connects to the first address, and starts the next attempt whenever
+// one failed or FallbackDelay passed. The first established connection is returned.
+func (d *Dialer) dialParallel(ctx context.Context, dialer *net.Dialer, network string, addrs []netip.AddrPort) (net.Conn, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	delay := d.FallbackDelay
+	if delay == 0 {
+		delay = 300 * time.Millisecond
+	}
+
+	type result struct {
+		conn net.Conn
+		err  error
+	}
+
+	results := make(chan result, len(addrs))
+	next := 0
+	pending := 0
+
+	// Every attempt gets a fresh timer, so no expiry of an earlier one can start the next attempt early
+	var fallback <-chan time.Time
+
+	start := func() {
+		addr := addrs[next]
+		next++
+		pending++
+
+		go func() {
+			conn, err := dialer.DialContext(ctx, network, addr.String())
+			results <- result{conn, err}
+		}()
+
+		fallback = time.After(delay)
+	}
+
+	start()
+
+	var firstErr error
+
+	for pending > 0 {
+		select {
+		case r := <-results:
+			pending--
+
+			if r.err == nil {
+				// Close connections that are established after this one
+				go func(pending int) {
+					for i := 0; i < pending; i++ {
+						if late := <-results; late.conn != nil {
+							late.conn.Close()
+						}
+					}
+				}(pending)
+
+				return r.conn, nil
+			}
+
+			if firstErr == nil {
+				firstErr = r.err
+			}
+
+			if next < len(addrs) {
+				start()
+			}
+
+		case <-fallback:
+			fallback = nil
+
+			if next < len(addrs) {
+				start()
+			}
+		}
+	}
+
+	return nil, firstErr
+}
@@ -0,0 +1,23 @@
+package avahi
+
+import "testing"
+
+func TestSplitInstanceName(t *testing.T) {
+	tests := []struct {
+		name, instance, serviceType, domain string
+		ok                                  bool
+	}{
+		{"Printer._ipp._tcp.local", "Printer", "_ipp._tcp", "local", true},
+		{`Office\032Printer._ipp._TCP.example.com.`, "Office Printer", "_ipp._TCP", "example.com", true},
+		{"Printer.ipp._tcp.local", "", "", "", false},
+		{"_ipp._tcp.local", "", "", "", false},
+		{"printer.local", "", "", "", false},
+	}
+
+	for _, test := range tests {
+		instance, serviceType, domain, ok := splitInstanceName(test.name)
+		if instance != test.instance || serviceType != test.serviceType || domain != test.domain || ok != test.ok {
+			t.Errorf("splitInstanceName(%q) returned %q, %q, %q, %v", test.name, instance, serviceType, domain, ok)
+		}
+	}
+}
@@ -11,7 +11,17 @@ type DomainBrowser struct {
 	object        dbus.BusObject
 	AddChannel    chan Domain
 	RemoveChannel chan Domain
-	closeCh       chan struct{}
+
+	// AllForNowChannel receives a value once the initial cache dump is complete
+	// and no further items are expected in the near future.
+	AllForNowChannel chan struct{}
+	// CacheExhaustedChannel receives a value once all entries from the daemon's cache have been delivered.
+	CacheExhaustedChannel chan struct{}
+	// FailureChannel receives the error reported by the daemon when browsing failed.
+	FailureChannel chan error
+
+	closeCh chan struct{}
+	queue   *signalQueue
 }
 
 const (
@@ -35,6 +45,10 @@ func DomainBrowserNew(conn *dbus.Conn, path dbus.ObjectPath) (*DomainBrowser, er
 	c.AddChannel = make(chan Domain)
 	c.RemoveChannel = make(chan Domain)
 	c.closeCh = make(chan struct{})
+	c.queue = newSignalQueue()
+	c.AllForNowChannel = make(chan struct{}, 1)
+	c.CacheExhaustedChannel = make(chan struct{}, 1)
+	c.FailureChannel = make(chan error, 1)
 
 	return c, nil
 }
@@ -50,11 +64,54 @@ func (c *DomainBrowser) free() {
 	c.object.Call(c.interfaceForMember("Free"), 0)
 }
 
+// Dropped returns the number of signals discarded because the queue was full
+func (c *DomainBrowser) Dropped() uint64 {
+	return c.queue.droppedCount()
+}
+
+func (c *DomainBrowser) getSignalQueue() *signalQueue {
+	return c.queue
+}
+
 func (c *DomainBrowser) getObjectPath() dbus.ObjectPath {
 	return c.object.Path()
 }
 
+func (c *DomainBrowser) restore(conn *dbus.Conn, path dbus.ObjectPath) error {
+	c.object = conn.Object("org.freedesktop.Avahi", path)
+	return nil
+}
+
 func (c *DomainBrowser) dispatchSignal(signal *dbus.Signal) error {
+	switch signal.Name {
+	case c.interfaceForMember("AllForNow"):
+		select {
+		case c.AllForNowChannel <- struct{}{}:
+		default:
+		}
+		return nil
+
+	case c.interfaceForMember("CacheExhausted"):
+		select {
+		case c.CacheExhaustedChannel <- struct{}{}:
+		default:
+		}
+		return nil
+
+	case c.interfaceForMember("Failure"):
+		var e string
+		err := dbus.Store(signal.Body, &e)
+		if err != nil {
+			return err
+		}
+
+		select {
+		case c.FailureChannel <- avahiErrorFromString(e):
+		default:
+		}
+		return nil
+	}
+
 	if signal.Name == c.interfaceForMember("ItemNew") || signal.Name == c.interfaceForMember("ItemRemove") {
 		var domain Domain
 		err := dbus.Store(signal.Body, &domain.Interface, &domain.Protocol, &domain.Domain, &domain.Flags)
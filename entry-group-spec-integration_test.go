@@ -0,0 +1,63 @@
+package avahi_test
+
+import (
+	"testing"
+
+	"github.com/holoplot/go-avahi"
+	"github.com/holoplot/go-avahi/avahitest"
+)
+
+func TestEntryGroupSpec(t *testing.T) {
+	d, s := avahitest.NewServer(t)
+
+	eg, err := s.EntryGroupNew()
+	if err != nil {
+		t.Fatalf("EntryGroupNew() failed: %v", err)
+	}
+
+	spec := avahi.EntryGroupSpec{
+		Services: []avahi.ServiceSpec{{
+			Interface: avahi.InterfaceUnspec,
+			Protocol:  avahi.ProtoUnspec,
+			Name:      "Printer",
+			Type:      "_ipp._tcp",
+			Port:      631,
+			Txt:       [][]byte{[]byte("txtvers=1")},
+			Subtypes:  []string{"_color"},
+		}},
+	}
+
+	err = eg.ApplySpec(spec)
+	if err != nil {
+		t.Fatalf("ApplySpec() failed: %v", err)
+	}
+
+	spec.Services[0].Txt = [][]byte{[]byte("txtvers=2")}
+
+	err = eg.UpdateSpec(spec)
+	if err != nil {
+		t.Fatalf("UpdateSpec() failed: %v", err)
+	}
+
+	services := d.Services()
+	if len(services) != 1 || string(services[0].Txt[0]) != "txtvers=2" {
+		t.Fatalf("services after TXT update: %+v", services)
+	}
+
+	state, err := eg.GetState()
+	if err != nil || state != avahi.EntryGroupEstablished {
+		t.Fatalf("GetState() after TXT update returned %d, %v", state, err)
+	}
+
+	spec.Services[0].Port = 632
+
+	err = eg.UpdateSpec(spec)
+	if err != nil {
+		t.Fatalf("UpdateSpec() failed: %v", err)
+	}
+
+	services = d.Services()
+	if len(services) != 1 || services[0].Port != 632 {
+		t.Fatalf("services after re-registering: %+v", services)
+	}
+}
@@ -0,0 +1,325 @@
+package avahi
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"strings"
+)
+
+// A ServiceSpec declares a service of an EntryGroupSpec
+type ServiceSpec struct {
+	Interface int32
+	Protocol  int32
+	Flags     uint32
+	Name      string
+	Type      string
+	Domain    string
+	Host      string
+	Port      uint16
+	Txt       [][]byte
+	// Subtypes are either complete, like "_color._sub._ipp._tcp", or just the subtype label, like "_color"
+	Subtypes []string
+}
+
+// An AddressSpec declares a host name and address pair of an EntryGroupSpec
+type AddressSpec struct {
+	Interface int32
+	Protocol  int32
+	Flags     uint32
+	Name      string
+	Address   string
+}
+
+// A RecordSpec declares a raw record of an EntryGroupSpec
+type RecordSpec struct {
+	Interface int32
+	Protocol  int32
+	Flags     uint32
+	Name      string
+	Class     uint16
+	Type      uint16
+	TTL       uint32
+	Rdata     []byte
+}
+
+// An EntryGroupSpec declares the complete contents of an EntryGroup
+type EntryGroupSpec struct {
+	Services  []ServiceSpec
+	Addresses []AddressSpec
+	Records   []RecordSpec
+}
+
+// An EntryGroupSpecDiff describes how to get from one EntryGroupSpec to another
+type EntryGroupSpecDiff struct {
+	// Reregister is set if anything but TXT records changed, so the group must be registered again
+	Reregister bool
+	// TxtChanged holds the services of the new spec whose TXT record changed.
+	// It is only filled if Reregister is not set.
+	TxtChanged []ServiceSpec
+}
+
+type serviceSpecKey struct {
+	Interface int32
+	Protocol  int32
+	Name      string
+	Type      string
+	Domain    string
+}
+
+func (s ServiceSpec) key() serviceSpecKey {
+	return serviceSpecKey{s.Interface, s.Protocol, s.Name, strings.ToLower(s.Type), strings.ToLower(s.Domain)}
+}
+
+// subtype returns the complete name of the subtype st
+func (s ServiceSpec) subtype(st string) string {
+	if strings.Contains(st, "._sub.") {
+		return st
+	}
+
+	return st + "._sub." + s.Type
+}
+
+// Validate checks the spec for errors the daemon would report when it is applied.
+// The returned errors match the corresponding sentinel errors with errors.Is.
+func (s EntryGroupSpec) Validate() error {
+	services := make(map[serviceSpecKey]bool)
+
+	for _, service := range s.Services {
+		if service.Name == "" || len(service.Name) > 63 {
+			return fmt.Errorf("%w: %q", ErrInvalidServiceName, service.Name)
+		}
+
+		labels, err := splitName(service.Type)
+		if err != nil || len(labels) != 2 || !serviceTypeLabels(labels) {
+			return fmt.Errorf("%w: %q", ErrInvalidServiceType, service.Type)
+		}
+
+		if services[service.key()] {
+			return fmt.Errorf("%w: service %q of type %s is declared twice", ErrCollision, service.Name, service.Type)
+		}
+		services[service.key()] = true
+
+		for _, txt := range service.Txt {
+			if len(txt) > TxtMaxStringSize {
+				return fmt.Errorf("%w: service %q has a string of %d bytes", ErrTxtStringTooLong, service.Name, len(txt))
+			}
+		}
+
+		for _, st := range service.Subtypes {
+			labels, err := splitName(service.subtype(st))
+			if err != nil || len(labels) != 4 || !serviceTypeLabels(labels) || !DomainEqual(joinName(labels[2:]), service.Type) {
+				return fmt.Errorf("%w: %q of service %q", ErrInvalidServiceSubtype, st, service.Name)
+			}
+		}
+	}
+
+	for _, address := range s.Addresses {
+		if address.Name == "" {
+			return fmt.Errorf("%w: empty name for %s", ErrInvalidHostName, address.Address)
+		}
+
+		if net.ParseIP(address.Address) == nil {
+			return fmt.Errorf("%w: %q", ErrInvalidAddress, address.Address)
+		}
+	}
+
+	for _, record := range s.Records {
+		if record.Name == "" || len(record.Rdata) > 0xffff {
+			return fmt.Errorf("%w: %q", ErrInvalidRecord, record.Name)
+		}
+	}
+
+	return nil
+}
+
+// sameExceptTxt reports whether a and b only differ in their TXT records
+func sameExceptTxt(a, b ServiceSpec) bool {
+	if a.key() != b.key() || a.Flags != b.Flags || a.Host != b.Host || a.Port != b.Port ||
+		len(a.Subtypes) != len(b.Subtypes) {
+		return false
+	}
+
+	for i := range a.Subtypes {
+		if a.subtype(a.Subtypes[i]) != b.subtype(b.Subtypes[i]) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func sameTxt(a, b [][]byte) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i := range a {
+		if !bytes.Equal(a[i], b[i]) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Diff compares the spec to a newer one
+func (s EntryGroupSpec) Diff(newer EntryGroupSpec) EntryGroupSpecDiff {
+	var diff EntryGroupSpecDiff
+
+	if len(s.Services) != len(newer.Services) || len(s.Addresses) != len(newer.Addresses) ||
+		len(s.Records) != len(newer.Records) {
+		return EntryGroupSpecDiff{Reregister: true}
+	}
+
+	for i, address := range s.Addresses {
+		if address != newer.Addresses[i] {
+			return EntryGroupSpecDiff{Reregister: true}
+		}
+	}
+
+	for i, record := range s.Records {
+		r := newer.Records[i]
+		if record.Interface != r.Interface || record.Protocol != r.Protocol || record.Flags != r.Flags ||
+			record.Name != r.Name || record.Class != r.Class || record.Type != r.Type ||
+			record.TTL != r.TTL || !bytes.Equal(record.Rdata, r.Rdata) {
+			return EntryGroupSpecDiff{Reregister: true}
+		}
+	}
+
+	for i, service := range s.Services {
+		n := newer.Services[i]
+		if !sameExceptTxt(service, n) {
+			return EntryGroupSpecDiff{Reregister: true}
+		}
+
+		if !sameTxt(service.Txt, n.Txt) {
+			diff.TxtChanged = append(diff.TxtChanged, n)
+		}
+	}
+
+	return diff
+}
+
+// clone returns a copy of s that shares no memory with it
+func (s EntryGroupSpec) clone() EntryGroupSpec {
+	c := EntryGroupSpec{
+		Services:  make([]ServiceSpec, len(s.Services)),
+		Addresses: append([]AddressSpec(nil), s.Addresses...),
+		Records:   make([]RecordSpec, len(s.Records)),
+	}
+
+	for i, service := range s.Services {
+		service.Subtypes = append([]string(nil), service.Subtypes...)
+		txt := make([][]byte, len(service.Txt))
+		for j := range service.Txt {
+			txt[j] = append([]byte(nil), service.Txt[j]...)
+		}
+		service.Txt = txt
+		c.Services[i] = service
+	}
+
+	for i, record := range s.Records {
+		record.Rdata = append([]byte(nil), record.Rdata...)
+		c.Records[i] = record
+	}
+
+	return c
+}
+
+// ApplySpec validates spec, replaces the contents of the group with it and commits the group.
+// If any step fails, the group is reset, so it is never left half-populated.
+func (c *EntryGroup) ApplySpec(spec EntryGroupSpec) error {
+	err := spec.Validate()
+	if err != nil {
+		return err
+	}
+
+	err = c.Reset()
+	if err == nil {
+		err = c.addSpec(spec)
+	}
+	if err == nil {
+		err = c.Commit()
+	}
+
+	if err != nil {
+		_ = c.Reset()
+		return err
+	}
+
+	c.mutex.Lock()
+	c.spec = spec.clone()
+	c.hasSpec = true
+	c.mutex.Unlock()
+
+	return nil
+}
+
+// UpdateSpec changes the group from the spec last applied to spec. If only TXT records changed,
+// they are updated in place with UpdateServiceTxt, otherwise the group is registered again
+// with ApplySpec. Groups that had no spec applied since the last Reset are always registered again.
+func (c *EntryGroup) UpdateSpec(spec EntryGroupSpec) error {
+	err := spec.Validate()
+	if err != nil {
+		return err
+	}
+
+	c.mutex.Lock()
+	old, hasSpec := c.spec, c.hasSpec
+	c.mutex.Unlock()
+
+	if !hasSpec {
+		return c.ApplySpec(spec)
+	}
+
+	diff := old.Diff(spec)
+	if diff.Reregister {
+		return c.ApplySpec(spec)
+	}
+
+	for _, s := range diff.TxtChanged {
+		err = c.UpdateServiceTxt(s.Interface, s.Protocol, s.Flags, s.Name, s.Type, s.Domain, s.Txt)
+		if err != nil {
+			return c.ApplySpec(spec)
+		}
+	}
+
+	c.mutex.Lock()
+	c.spec = spec.clone()
+	c.mutex.Unlock()
+
+	return nil
+}
+
+func (c *EntryGroup) addSpec(spec EntryGroupSpec) error {
+	for _, s := range spec.Services {
+		err := c.AddService(s.Interface, s.Protocol, s.Flags, s.Name, s.Type, s.Domain, s.Host, s.Port, s.Txt)
+		if err != nil {
+			return fmt.Errorf("service %q: %w", s.Name, err)
+		}
+
+		for _, st := range s.Subtypes {
+			err = c.AddServiceSubtype(s.Interface, s.Protocol, s.Flags, s.Name, s.Type, s.Domain, s.subtype(st))
+			if err != nil {
+				return fmt.Errorf("subtype %q of service %q: %w", st, s.Name, err)
+			}
+		}
+	}
+
+	for _, a := range spec.Addresses {
+		err := c.AddAddress(a.Interface, a.Protocol, a.Flags, a.Name, a.Address)
+		if err != nil {
+			return fmt.Errorf("address %s of %q: %w", a.Address, a.Name, err)
+		}
+	}
+
+	for _, r := range spec.Records {
+		err := c.AddRecord(r.Interface, r.Protocol, r.Flags, r.Name, r.Class, r.Type, r.TTL, r.Rdata)
+		if err != nil {
+			return fmt.Errorf("record %q: %w", r.Name, err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,88 @@
+package avahi
+
+import (
+	"errors"
+	"testing"
+)
+
+func testSpec() EntryGroupSpec {
+	return EntryGroupSpec{
+		Services: []ServiceSpec{{
+			Interface: InterfaceUnspec,
+			Protocol:  ProtoUnspec,
+			Name:      "Printer",
+			Type:      "_ipp._tcp",
+			Port:      631,
+			Txt:       [][]byte{[]byte("txtvers=1")},
+			Subtypes:  []string{"_color", "_duplex._sub._ipp._tcp"},
+		}},
+		Addresses: []AddressSpec{{Interface: InterfaceUnspec, Protocol: ProtoUnspec, Name: "printer.local", Address: "192.168.1.20"}},
+	}
+}
+
+func TestEntryGroupSpecValidate(t *testing.T) {
+	if err := testSpec().Validate(); err != nil {
+		t.Fatalf("Validate() failed: %v", err)
+	}
+
+	for _, tc := range []struct {
+		modify func(*EntryGroupSpec)
+		err    error
+	}{
+		{func(s *EntryGroupSpec) { s.Services[0].Name = "" }, ErrInvalidServiceName},
+		{func(s *EntryGroupSpec) { s.Services[0].Type = "_ipp._sctp" }, ErrInvalidServiceType},
+		{func(s *EntryGroupSpec) { s.Services[0].Type = "ipp._tcp" }, ErrInvalidServiceType},
+		{func(s *EntryGroupSpec) { s.Services[0].Type = "_\x01._tcp" }, ErrInvalidServiceType},
+		{func(s *EntryGroupSpec) { s.Services[0].Type = "_123._tcp" }, ErrInvalidServiceType},
+		{func(s *EntryGroupSpec) { s.Services[0].Type = "_abcdefghijklmnop._tcp" }, ErrInvalidServiceType},
+		{func(s *EntryGroupSpec) { s.Services[0].Type = "_ip\\.p._tcp" }, ErrInvalidServiceType},
+		{func(s *EntryGroupSpec) { s.Services[0].Type = "_ipp._tcp.local" }, ErrInvalidServiceType},
+		{func(s *EntryGroupSpec) { s.Services[0].Subtypes = []string{"_color._sub._http._tcp"} }, ErrInvalidServiceSubtype},
+		{func(s *EntryGroupSpec) { s.Services[0].Txt = [][]byte{make([]byte, 256)} }, ErrTxtStringTooLong},
+		{func(s *EntryGroupSpec) { s.Services = append(s.Services, s.Services[0]) }, ErrCollision},
+		{func(s *EntryGroupSpec) { s.Addresses[0].Address = "printer" }, ErrInvalidAddress},
+		{func(s *EntryGroupSpec) { s.Records = []RecordSpec{{Class: DNSClassIN, Type: DNSTypeTXT}} }, ErrInvalidRecord},
+	} {
+		s := testSpec()
+		tc.modify(&s)
+
+		if err := s.Validate(); !errors.Is(err, tc.err) {
+			t.Errorf("Validate() of %+v returned %v, expected %v", s, err, tc.err)
+		}
+	}
+}
+
+func TestEntryGroupSpecDiff(t *testing.T) {
+	old := testSpec()
+
+	if diff := old.Diff(testSpec()); diff.Reregister || len(diff.TxtChanged) != 0 {
+		t.Fatalf("Diff() of equal specs returned %+v", diff)
+	}
+
+	newer := testSpec()
+	newer.Services[0].Txt = [][]byte{[]byte("txtvers=2")}
+
+	if diff := old.Diff(newer); diff.Reregister || len(diff.TxtChanged) != 1 || string(diff.TxtChanged[0].Txt[0]) != "txtvers=2" {
+		t.Fatalf("Diff() of a TXT change returned %+v", diff)
+	}
+
+	newer = testSpec()
+	newer.Services[0].Subtypes = []string{"_color._sub._ipp._tcp", "_duplex"}
+
+	if diff := old.Diff(newer); diff.Reregister {
+		t.Fatalf("Diff() of equivalent subtypes returned %+v", diff)
+	}
+
+	newer.Services[0].Port = 632
+
+	if diff := old.Diff(newer); !diff.Reregister {
+		t.Fatalf("Diff() of a port change returned %+v", diff)
+	}
+
+	newer = testSpec()
+	newer.Addresses = nil
+
+	if diff := old.Diff(newer); !diff.Reregister {
+		t.Fatalf("Diff() of a removed address returned %+v", diff)
+	}
+}
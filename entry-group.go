@@ -2,6 +2,7 @@ package avahi
 
 import (
 	"fmt"
+	"sync"
 
 	dbus "github.com/godbus/dbus/v5"
 )
@@ -25,11 +26,34 @@ type EntryGroupState struct {
 	Error string
 }
 
+// Err returns Error as an *Error, or nil if it reports no error.
+// The daemon reports ErrCollision for EntryGroupCollision.
+func (s EntryGroupState) Err() error {
+	return avahiErrorFromString(s.Error)
+}
+
+// entryGroupCall is a recorded call that populated an EntryGroup
+type entryGroupCall struct {
+	method string
+	args   []interface{}
+}
+
 // An EntryGroup describes a group of records for services
 type EntryGroup struct {
 	conn               *dbus.Conn
 	object             dbus.BusObject
 	StateChangeChannel chan EntryGroupState
+
+	mutex     sync.Mutex
+	calls     []entryGroupCall
+	committed bool
+
+	// spec is the EntryGroupSpec last applied, if hasSpec is set
+	spec    EntryGroupSpec
+	hasSpec bool
+
+	closeCh chan struct{}
+	queue   *signalQueue
 }
 
 // EntryGroupNew creates a new entry group
@@ -38,6 +62,8 @@ func EntryGroupNew(conn *dbus.Conn, path dbus.ObjectPath) (*EntryGroup, error) {
 	c.conn = conn
 	c.object = c.conn.Object("org.freedesktop.Avahi", path)
 	c.StateChangeChannel = make(chan EntryGroupState, 10)
+	c.closeCh = make(chan struct{})
+	c.queue = newSignalQueue()
 
 	return c, nil
 }
@@ -49,21 +75,46 @@ func (c *EntryGroup) interfaceForMember(method string) string {
 // Commit an AvahiEntryGroup. The entries in the entry group are now registered on the network.
 // Commiting empty entry groups is considered an error.
 func (c *EntryGroup) Commit() error {
-	return c.object.Call(c.interfaceForMember("Commit"), 0).Err
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	err := c.object.Call(c.interfaceForMember("Commit"), 0).Err
+	if err != nil {
+		return avahiError(err)
+	}
+
+	c.committed = true
+
+	return nil
 }
 
 // Reset an AvahiEntryGroup. This takes effect immediately.
 func (c *EntryGroup) Reset() error {
-	return c.object.Call(c.interfaceForMember("Reset"), 0).Err
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	err := c.object.Call(c.interfaceForMember("Reset"), 0).Err
+	if err != nil {
+		return avahiError(err)
+	}
+
+	c.calls = nil
+	c.committed = false
+	c.hasSpec = false
+
+	return nil
 }
 
 // GetState gets an AvahiEntryGroup's state
 func (c *EntryGroup) GetState() (int32, error) {
 	var i int32
 
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
 	err := c.object.Call(c.interfaceForMember("GetState"), 0).Store(&i)
 	if err != nil {
-		return 0, err
+		return 0, avahiError(err)
 	}
 
 	return i, nil
@@ -73,9 +124,12 @@ func (c *EntryGroup) GetState() (int32, error) {
 func (c *EntryGroup) IsEmpty() (bool, error) {
 	var b bool
 
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
 	err := c.object.Call(c.interfaceForMember("IsEmpty"), 0).Store(&b)
 	if err != nil {
-		return false, err
+		return false, avahiError(err)
 	}
 
 	return b, nil
@@ -84,39 +138,119 @@ func (c *EntryGroup) IsEmpty() (bool, error) {
 // AddService adds a service. Takes a list of TXT record strings as last arguments.
 // Please note that this service is not announced on the network before Commit() is called.
 func (c *EntryGroup) AddService(iface, protocol int32, flags uint32, name, serviceType, domain, host string, port uint16, txt [][]byte) error {
-	return c.object.Call(c.interfaceForMember("AddService"), 0, iface, protocol, flags, name, serviceType, domain, host, port, txt).Err
+	return c.call("AddService", iface, protocol, flags, name, serviceType, domain, host, port, txt)
+}
+
+// AddServiceWithOptions adds a service like AddService. It accepts WithInterface, WithInterfaceName,
+// WithProtocol, WithDomain, WithHost and WithPublishFlags.
+func (c *EntryGroup) AddServiceWithOptions(name, serviceType string, port uint16, txt [][]byte, opts ...Option) error {
+	o := optionsOf(opts)
+
+	if o.ifaceName != "" {
+		index, err := interfaceIndexByName(c.conn, o.ifaceName)
+		if err != nil {
+			return err
+		}
+
+		o.iface = index
+	}
+
+	return c.AddService(o.iface, o.protocol, o.flags, name, serviceType, o.domain, o.host, port, txt)
 }
 
 // AddServiceSubtype adds a subtype for a service. The service should already be existent in the entry group.
 // You may add as many subtypes for a service as you wish.
 func (c *EntryGroup) AddServiceSubtype(iface, protocol int32, flags uint32, name, serviceType, domain, subtype string) error {
-	return c.object.Call(c.interfaceForMember("AddServiceSubtype"), 0, iface, protocol, flags, name, serviceType, domain, subtype).Err
+	return c.call("AddServiceSubtype", iface, protocol, flags, name, serviceType, domain, subtype)
 }
 
 // UpdateServiceTxt apdates a TXT record for an existing service.
 // The service should already be existent in the entry group.
 func (c *EntryGroup) UpdateServiceTxt(iface, protocol int32, flags uint32, name, serviceType, domain string, txt [][]byte) error {
-	return c.object.Call(c.interfaceForMember("UpdateServiceTxt"), 0, iface, protocol, flags, name, serviceType, domain, txt).Err
+	return c.call("UpdateServiceTxt", iface, protocol, flags, name, serviceType, domain, txt)
 }
 
 // AddAddress add a host/address pair to the entry group
 func (c *EntryGroup) AddAddress(iface, protocol int32, flags uint32, name, address string) error {
-	return c.object.Call(c.interfaceForMember("AddAddress"), 0, iface, protocol, flags, name, address).Err
+	return c.call("AddAddress", iface, protocol, flags, name, address)
 }
 
 // AddRecord adds an arbitrary record. I hope you know what you do.
 func (c *EntryGroup) AddRecord(iface, protocol int32, flags uint32, name string, class, recordType uint16, ttl uint32, rdata []byte) error {
-	return c.object.Call(c.interfaceForMember("AddRecord"), 0, iface, protocol, flags, name, class, recordType, ttl, rdata).Err
+	return c.call("AddRecord", iface, protocol, flags, name, class, recordType, ttl, rdata)
+}
+
+// call invokes a method that adds to the group and records it, so the
+// group can be re-populated after avahi-daemon restarted.
+func (c *EntryGroup) call(method string, args ...interface{}) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	err := c.object.Call(c.interfaceForMember(method), 0, args...).Err
+	if err != nil {
+		return avahiError(err)
+	}
+
+	c.calls = append(c.calls, entryGroupCall{method: method, args: args})
+
+	return nil
+}
+
+// AddRdata adds a record of class DNSClassIN with the type and encoded data of rdata.
+// See AddRecord.
+func (c *EntryGroup) AddRdata(iface, protocol int32, flags uint32, name string, ttl uint32, rdata Rdata) error {
+	b, err := rdata.Encode()
+	if err != nil {
+		return err
+	}
+
+	return c.AddRecord(iface, protocol, flags, name, DNSClassIN, rdata.RecordType(), ttl, b)
 }
 
 func (c *EntryGroup) free() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	close(c.closeCh)
 	c.object.Call(c.interfaceForMember("Free"), 0)
 }
 
+// Dropped returns the number of signals discarded because the queue was full
+func (c *EntryGroup) Dropped() uint64 {
+	return c.queue.droppedCount()
+}
+
+func (c *EntryGroup) getSignalQueue() *signalQueue {
+	return c.queue
+}
+
 func (c *EntryGroup) getObjectPath() dbus.ObjectPath {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
 	return c.object.Path()
 }
 
+func (c *EntryGroup) restore(conn *dbus.Conn, path dbus.ObjectPath) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.object = conn.Object("org.freedesktop.Avahi", path)
+
+	for _, call := range c.calls {
+		err := c.object.Call(c.interfaceForMember(call.method), 0, call.args...).Err
+		if err != nil {
+			return avahiError(err)
+		}
+	}
+
+	if c.committed {
+		return avahiError(c.object.Call(c.interfaceForMember("Commit"), 0).Err)
+	}
+
+	return nil
+}
+
 func (c *EntryGroup) dispatchSignal(signal *dbus.Signal) error {
 	if signal.Name == c.interfaceForMember("StateChanged") {
 		var state EntryGroupState
@@ -125,7 +259,10 @@ func (c *EntryGroup) dispatchSignal(signal *dbus.Signal) error {
 			return err
 		}
 
-		c.StateChangeChannel <- state
+		select {
+		case c.StateChangeChannel <- state:
+		case <-c.closeCh:
+		}
 	}
 
 	return nil
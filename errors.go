@@ -0,0 +1,167 @@
+package avahi
+
+import (
+	"errors"
+	"strings"
+
+	dbus "github.com/godbus/dbus/v5"
+)
+
+// An Error is an error reported by avahi-daemon, either in reply to a method
+// call or as the error name of a Failure or StateChanged signal.
+// Errors with the same Name match each other with errors.Is, so any error
+// returned by this package can be compared against the sentinels below.
+// Errors from method calls unwrap to the original dbus.Error.
+type Error struct {
+	// Name is the D-Bus error name, e.g. org.freedesktop.Avahi.CollisionError.
+	// It is empty for error strings of signals that match none of the sentinels.
+	Name string
+	// Message is the error string sent by the daemon
+	Message string
+
+	cause error
+}
+
+func (e *Error) Error() string {
+	if e.Message == "" {
+		return e.Name
+	}
+
+	return e.Message
+}
+
+// Is reports whether target is an *Error with the same, non-empty Name
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Name != "" && t.Name == e.Name
+}
+
+// Unwrap returns the dbus.Error the daemon replied with, if any
+func (e *Error) Unwrap() error {
+	return e.cause
+}
+
+func errorNew(name, message string) *Error {
+	return &Error{Name: "org.freedesktop.Avahi." + name, Message: message}
+}
+
+// The errors avahi-daemon reports, with the messages of avahi_strerror()
+var (
+	ErrFailure               = errorNew("Failure", "Operation failed")
+	ErrBadState              = errorNew("BadStateError", "Invalid state")
+	ErrInvalidHostName       = errorNew("InvalidHostNameError", "Invalid host name")
+	ErrInvalidDomainName     = errorNew("InvalidDomainNameError", "Invalid domain name")
+	ErrNoNetwork             = errorNew("NoNetworkError", "No suitable network protocol available")
+	ErrInvalidTTL            = errorNew("InvalidTTLError", "Invalid DNS TTL")
+	ErrIsPattern             = errorNew("IsPatternError", "Resource record key is pattern")
+	ErrCollision             = errorNew("CollisionError", "Local name collision")
+	ErrInvalidRecord         = errorNew("InvalidRecordError", "Invalid record")
+	ErrInvalidServiceName    = errorNew("InvalidServiceNameError", "Invalid service name")
+	ErrInvalidServiceType    = errorNew("InvalidServiceTypeError", "Invalid service type")
+	ErrInvalidPort           = errorNew("InvalidPortError", "Invalid port number")
+	ErrInvalidKey            = errorNew("InvalidKeyError", "Invalid key")
+	ErrInvalidAddress        = errorNew("InvalidAddressError", "Invalid address")
+	ErrTimeout               = errorNew("TimeoutError", "Timeout reached")
+	ErrTooManyClients        = errorNew("TooManyClientsError", "Too many clients")
+	ErrTooManyObjects        = errorNew("TooManyObjectsError", "Too many objects")
+	ErrTooManyEntries        = errorNew("TooManyEntriesError", "Too many entries")
+	ErrOS                    = errorNew("OSError", "OS Error")
+	ErrAccessDenied          = errorNew("AccessDeniedError", "Access denied")
+	ErrInvalidOperation      = errorNew("InvalidOperationError", "Invalid operation")
+	ErrDBus                  = errorNew("DBusError", "An unexpected D-Bus error occurred")
+	ErrDisconnected          = errorNew("DisconnectedError", "Daemon connection failed")
+	ErrNoMemory              = errorNew("NoMemoryError", "Memory exhausted")
+	ErrInvalidObject         = errorNew("InvalidObjectError", "The object passed in was not valid")
+	ErrNoDaemon              = errorNew("NoDaemonError", "Daemon not running")
+	ErrInvalidInterface      = errorNew("InvalidInterfaceError", "Invalid interface index")
+	ErrInvalidProtocol       = errorNew("InvalidProtocolError", "Invalid protocol specification")
+	ErrInvalidFlags          = errorNew("InvalidFlagsError", "Invalid flags")
+	ErrNotFound              = errorNew("NotFoundError", "Not found")
+	ErrInvalidConfig         = errorNew("InvalidConfigError", "Invalid configuration")
+	ErrVersionMismatch       = errorNew("VersionMismatchError", "Version mismatch")
+	ErrInvalidServiceSubtype = errorNew("InvalidServiceSubtypeError", "Invalid service subtype")
+	ErrInvalidPacket         = errorNew("InvalidPacketError", "Invalid packet")
+	ErrInvalidDNSReturnCode  = errorNew("InvalidDNSReturnCodeError", "Invalid DNS return code")
+	ErrDNSFormErr            = errorNew("DNSFORMERR", "DNS failure: FORMERR")
+	ErrDNSServFail           = errorNew("DNSSERVFAIL", "DNS failure: SERVFAIL")
+	ErrDNSNXDomain           = errorNew("DNSNXDOMAIN", "DNS failure: NXDOMAIN")
+	ErrDNSNotImp             = errorNew("DNSNOTIMP", "DNS failure: NOTIMP")
+	ErrDNSRefused            = errorNew("DNSREFUSED", "DNS failure: REFUSED")
+	ErrDNSYXDomain           = errorNew("DNSYXDOMAIN", "DNS failure: YXDOMAIN")
+	ErrDNSYXRRSet            = errorNew("DNSYXRRSET", "DNS failure: YXRRSET")
+	ErrDNSNXRRSet            = errorNew("DNSNXRRSET", "DNS failure: NXRRSET")
+	ErrDNSNotAuth            = errorNew("DNSNOTAUTH", "DNS failure: NOTAUTH")
+	ErrDNSNotZone            = errorNew("DNSNOTZONE", "DNS failure: NOTZONE")
+	ErrInvalidRdata          = errorNew("InvalidRDataError", "Invalid RDATA")
+	ErrInvalidDNSClass       = errorNew("InvalidDNSClassError", "Invalid DNS class")
+	ErrInvalidDNSType        = errorNew("InvalidDNSTypeError", "Invalid DNS type")
+	ErrNotSupported          = errorNew("NotSupportedError", "Not supported")
+	ErrNotPermitted          = errorNew("NotPermittedError", "Operation not permitted")
+	ErrInvalidArgument       = errorNew("InvalidArgumentError", "Invalid argument")
+	ErrIsEmpty               = errorNew("IsEmptyError", "Is empty")
+	ErrNoChange              = errorNew("NoChangeError", "The requested operation is invalid because it is redundant")
+)
+
+var errorTable = []*Error{
+	ErrFailure, ErrBadState, ErrInvalidHostName, ErrInvalidDomainName, ErrNoNetwork,
+	ErrInvalidTTL, ErrIsPattern, ErrCollision, ErrInvalidRecord, ErrInvalidServiceName,
+	ErrInvalidServiceType, ErrInvalidPort, ErrInvalidKey, ErrInvalidAddress, ErrTimeout,
+	ErrTooManyClients, ErrTooManyObjects, ErrTooManyEntries, ErrOS, ErrAccessDenied,
+	ErrInvalidOperation, ErrDBus, ErrDisconnected, ErrNoMemory, ErrInvalidObject,
+	ErrNoDaemon, ErrInvalidInterface, ErrInvalidProtocol, ErrInvalidFlags, ErrNotFound,
+	ErrInvalidConfig, ErrVersionMismatch, ErrInvalidServiceSubtype, ErrInvalidPacket,
+	ErrInvalidDNSReturnCode, ErrDNSFormErr, ErrDNSServFail, ErrDNSNXDomain, ErrDNSNotImp,
+	ErrDNSRefused, ErrDNSYXDomain, ErrDNSYXRRSet, ErrDNSNXRRSet, ErrDNSNotAuth,
+	ErrDNSNotZone, ErrInvalidRdata, ErrInvalidDNSClass, ErrInvalidDNSType,
+	ErrNotSupported, ErrNotPermitted, ErrInvalidArgument, ErrIsEmpty, ErrNoChange,
+}
+
+// avahiError converts a D-Bus error reply from avahi-daemon to an *Error.
+// Other errors are returned unchanged.
+func avahiError(err error) error {
+	var e dbus.Error
+	var p *dbus.Error
+
+	if errors.As(err, &p) && p != nil {
+		e = *p
+	} else if !errors.As(err, &e) {
+		return err
+	}
+
+	if !strings.HasPrefix(e.Name, "org.freedesktop.Avahi.") {
+		return err
+	}
+
+	message := ""
+	if len(e.Body) > 0 {
+		message, _ = e.Body[0].(string)
+	}
+
+	return &Error{Name: e.Name, Message: message, cause: err}
+}
+
+// errorSuccess is the error string avahi-daemon sends in signals that report no error
+const errorSuccess = "org.freedesktop.Avahi.Success"
+
+// avahiErrorFromString converts an error string from a signal to an *Error. avahi-daemon
+// sends D-Bus error names, messages of avahi_strerror() are recognized as well.
+// Unknown strings are reported as an *Error without Name.
+func avahiErrorFromString(s string) error {
+	if s == "" || s == errorSuccess {
+		return nil
+	}
+
+	for _, e := range errorTable {
+		if e.Name == s {
+			return &Error{Name: e.Name, Message: e.Message}
+		}
+	}
+
+	for _, e := range errorTable {
+		if e.Message == s {
+			return &Error{Name: e.Name, Message: s}
+		}
+	}
+
+	return &Error{Message: s}
+}
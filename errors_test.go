@@ -0,0 +1,69 @@
+package avahi
+
+import (
+	"errors"
+	"testing"
+
+	dbus "github.com/godbus/dbus/v5"
+)
+
+func TestAvahiError(t *testing.T) {
+	err := avahiError(dbus.Error{Name: "org.freedesktop.Avahi.CollisionError", Body: []interface{}{"Local name collision"}})
+	if !errors.Is(err, ErrCollision) || errors.Is(err, ErrNotFound) {
+		t.Fatalf("avahiError() returned %v, which does not match ErrCollision only", err)
+	}
+
+	var e *Error
+	if !errors.As(err, &e) || e.Name != ErrCollision.Name || e.Error() != "Local name collision" {
+		t.Fatalf("avahiError() returned %#v", err)
+	}
+
+	var cause dbus.Error
+	if !errors.As(err, &cause) || cause.Name != ErrCollision.Name {
+		t.Fatalf("avahiError() returned %#v, which does not unwrap to the dbus.Error", err)
+	}
+
+	err = avahiError(dbus.NewError("org.freedesktop.Avahi.TimeoutError", nil))
+	if !errors.Is(err, ErrTimeout) || err.Error() != ErrTimeout.Name {
+		t.Fatalf("avahiError() of a pointer returned %v", err)
+	}
+
+	other := dbus.Error{Name: "org.freedesktop.DBus.Error.ServiceUnknown"}
+	if err := avahiError(other); err.Error() != other.Error() || errors.As(err, &e) {
+		t.Fatalf("avahiError() converted a foreign error to %v", err)
+	}
+
+	if avahiError(nil) != nil {
+		t.Fatal("avahiError(nil) is not nil")
+	}
+}
+
+func TestAvahiErrorFromString(t *testing.T) {
+	state := EntryGroupState{State: EntryGroupCollision, Error: "org.freedesktop.Avahi.CollisionError"}
+	if err := state.Err(); !errors.Is(err, ErrCollision) || err.Error() != "Local name collision" {
+		t.Fatalf("Err() returned %v", err)
+	}
+
+	established := EntryGroupState{State: EntryGroupEstablished, Error: "org.freedesktop.Avahi.Success"}
+	if err := established.Err(); err != nil {
+		t.Fatalf("Err() of Success returned %v", err)
+	}
+
+	if err := avahiErrorFromString("Timeout reached"); !errors.Is(err, ErrTimeout) {
+		t.Fatalf("avahiErrorFromString() of a message returned %v", err)
+	}
+
+	var e *Error
+	err := avahiErrorFromString("Something odd")
+	if !errors.As(err, &e) || e.Name != "" || errors.Is(err, ErrFailure) || err.Error() != "Something odd" {
+		t.Fatalf("avahiErrorFromString() of an unknown string returned %#v", err)
+	}
+
+	if errors.Is(err, avahiErrorFromString("Something else")) {
+		t.Fatal("errors without Name match each other")
+	}
+
+	if err := (ServerState{State: ServerRunning}).Err(); err != nil {
+		t.Fatalf("Err() of an empty string returned %v", err)
+	}
+}
@@ -0,0 +1,194 @@
+// Package grpcresolver implements a gRPC name resolver for services found with DNS-SD.
+//
+// Targets have the form "avahi:///_service._tcp" or "avahi:///_service._tcp.domain".
+// All instances of the service type are browsed and resolved, and the client
+// connection is updated once the initial instances are resolved, even if there
+// are none, and whenever instances appear, change or disappear. Browsing
+// failures are reported to the client connection and browsing is retried.
+package grpcresolver
+
+import (
+	"fmt"
+	"net/netip"
+	"reflect"
+
+	"github.com/holoplot/go-avahi"
+	"google.golang.org/grpc/attributes"
+	"google.golang.org/grpc/resolver"
+)
+
+// Scheme is the scheme of targets handled by a Builder
+const Scheme = "avahi"
+
+// A Builder creates resolvers that browse with a Server
+type Builder struct {
+	server   *avahi.Server
+	iface    int32
+	protocol int32
+	flags    uint32
+}
+
+// NewBuilder creates a Builder that browses on all interfaces and protocols.
+// Pass it to grpc.WithResolvers, or register it with resolver.Register.
+func NewBuilder(server *avahi.Server) *Builder {
+	return NewBuilderWithParameters(server, avahi.InterfaceUnspec, avahi.ProtoUnspec, 0)
+}
+
+// NewBuilderWithParameters creates a Builder that browses on the given interface and protocol
+func NewBuilderWithParameters(server *avahi.Server, iface, protocol int32, flags uint32) *Builder {
+	return &Builder{
+		server:   server,
+		iface:    iface,
+		protocol: protocol,
+		flags:    flags,
+	}
+}
+
+// Scheme returns Scheme
+func (b *Builder) Scheme() string {
+	return Scheme
+}
+
+// Build starts browsing for the service type of target
+func (b *Builder) Build(target resolver.Target, cc resolver.ClientConn, opts resolver.BuildOptions) (resolver.Resolver, error) {
+	serviceType, domain, err := parseEndpoint(target.Endpoint())
+	if err != nil {
+		return nil, err
+	}
+
+	d, err := b.server.ServiceDirectoryNew(b.iface, b.protocol, serviceType, domain, b.flags)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &avahiResolver{
+		server:      b.server,
+		cc:          cc,
+		directory:   d,
+		events:      d.Subscribe(),
+		doneChannel: make(chan struct{}),
+	}
+
+	go r.run()
+
+	return r, nil
+}
+
+// parseEndpoint splits "_service._tcp.domain" into service type and domain
+func parseEndpoint(endpoint string) (string, string, error) {
+	name, serviceType, domain, err := avahi.ServiceNameSplit(endpoint)
+	if err == nil && name != "" {
+		err = fmt.Errorf("%w: %q names a service instance", avahi.ErrInvalidServiceType, endpoint)
+	}
+	if err != nil {
+		return "", "", fmt.Errorf("invalid %s target, expected a service type like _service._tcp: %w", Scheme, err)
+	}
+
+	return serviceType, domain, nil
+}
+
+type avahiResolver struct {
+	server      *avahi.Server
+	cc          resolver.ClientConn
+	directory   *avahi.ServiceDirectory
+	events      chan avahi.ServiceDirectoryEvent
+	doneChannel chan struct{}
+}
+
+// ResolveNow does nothing, as changes are pushed by the daemon
+func (r *avahiResolver) ResolveNow(resolver.ResolveNowOptions) {}
+
+// Close stops browsing
+func (r *avahiResolver) Close() {
+	r.server.ServiceDirectoryFree(r.directory)
+	<-r.doneChannel
+}
+
+func (r *avahiResolver) run() {
+	defer close(r.doneChannel)
+
+	for {
+		select {
+		case _, ok := <-r.events:
+			if !ok {
+				return
+			}
+
+			r.update()
+
+		case <-r.directory.AllForNowChannel:
+			// Nothing may have been found, the client connection waits for a first state anyway
+			r.update()
+
+		case err := <-r.directory.FailureChannel:
+			r.cc.ReportError(err)
+		}
+	}
+}
+
+// update pushes all currently known instances to the client connection
+func (r *avahiResolver) update() {
+	var state resolver.State
+
+	for _, entry := range r.directory.Snapshot() {
+		var endpoint resolver.Endpoint
+		seen := map[netip.AddrPort]bool{}
+
+		for _, s := range entry.Services {
+			addrPort, err := s.AddrPort(r.server)
+			if err != nil || seen[addrPort] {
+				continue
+			}
+
+			seen[addrPort] = true
+
+			endpoint.Addresses = append(endpoint.Addresses, resolver.Address{
+				Addr:       addrPort.String(),
+				Attributes: attributes.New(serviceKey{}, serviceAttribute(s)),
+			})
+		}
+
+		if len(endpoint.Addresses) == 0 {
+			continue
+		}
+
+		endpoint.Attributes = endpoint.Addresses[0].Attributes
+
+		state.Endpoints = append(state.Endpoints, endpoint)
+		state.Addresses = append(state.Addresses, endpoint.Addresses...)
+	}
+
+	err := r.cc.UpdateState(state)
+	if err != nil {
+		r.cc.ReportError(err)
+	}
+}
+
+type serviceKey struct{}
+
+// serviceAttribute holds the resolved service of an address. It has an Equal
+// method, as attributes are compared and Service is not comparable.
+type serviceAttribute avahi.Service
+
+func (a serviceAttribute) Equal(o interface{}) bool {
+	other, ok := o.(serviceAttribute)
+	return ok && reflect.DeepEqual(a, other)
+}
+
+// Service returns the resolved service an address or endpoint was created from,
+// given the Attributes of a resolver.Address or resolver.Endpoint
+func Service(attrs *attributes.Attributes) (avahi.Service, bool) {
+	a, ok := attrs.Value(serviceKey{}).(serviceAttribute)
+	return avahi.Service(a), ok
+}
+
+// Txt returns the parsed TXT record of the service an address or endpoint was
+// created from, given the Attributes of a resolver.Address or resolver.Endpoint
+func Txt(attrs *attributes.Attributes) avahi.Txt {
+	s, ok := Service(attrs)
+	if !ok {
+		return nil
+	}
+
+	return s.TxtRecord()
+}
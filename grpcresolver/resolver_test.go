@@ -0,0 +1,201 @@
+package grpcresolver
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/netip"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/holoplot/go-avahi"
+	"github.com/holoplot/go-avahi/avahitest"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/resolver"
+)
+
+func TestParseEndpoint(t *testing.T) {
+	tests := []struct {
+		endpoint, serviceType, domain string
+		ok                            bool
+	}{
+		{"_echo._tcp", "_echo._tcp", "", true},
+		{"_echo._tcp.example.com.", "_echo._tcp", "example.com", true},
+		{"echo", "", "", false},
+		{"echo._tcp", "", "", false},
+		{"_printer._sub._ipp._tcp", "_printer._sub._ipp._tcp", "", true},
+		{"Printer._ipp._tcp.local", "", "", false},
+		{"_echo\\.x._tcp", "", "", false},
+	}
+
+	for _, test := range tests {
+		serviceType, domain, err := parseEndpoint(test.endpoint)
+		if serviceType != test.serviceType || domain != test.domain || (err == nil) != test.ok {
+			t.Errorf("parseEndpoint(%q) returned %q, %q, %v", test.endpoint, serviceType, domain, err)
+		}
+	}
+}
+
+type testClientConn struct {
+	resolver.ClientConn
+	states chan resolver.State
+	errors chan error
+
+	// updateErr is returned by UpdateState
+	updateErr error
+}
+
+func newTestClientConn() *testClientConn {
+	return &testClientConn{
+		states: make(chan resolver.State, 10),
+		errors: make(chan error, 10),
+	}
+}
+
+func (cc *testClientConn) UpdateState(state resolver.State) error {
+	cc.states <- state
+	return cc.updateErr
+}
+
+func (cc *testClientConn) ReportError(err error) {
+	cc.errors <- err
+}
+
+func (cc *testClientConn) expectError(t *testing.T, target error) {
+	t.Helper()
+
+	timeout := time.After(5 * time.Second)
+
+	for {
+		select {
+		case err := <-cc.errors:
+			if errors.Is(err, target) {
+				return
+			}
+		case <-timeout:
+			t.Fatalf("no error reported, expected %v", target)
+		}
+	}
+}
+
+func testService(name string, addrPort netip.AddrPort) avahi.Service {
+	return avahi.Service{
+		Interface: 1,
+		Protocol:  avahi.ProtoInet,
+		Name:      name,
+		Type:      "_health._tcp",
+		Domain:    "local",
+		Host:      "health.local",
+		Aprotocol: avahi.ProtoInet,
+		Address:   addrPort.Addr().String(),
+		Port:      addrPort.Port(),
+		Txt:       [][]byte{[]byte("version=1")},
+		Flags:     avahi.LookupResultLocal,
+	}
+}
+
+func TestResolver(t *testing.T) {
+	d, s := avahitest.NewServer(t)
+
+	cc := newTestClientConn()
+
+	r, err := NewBuilder(s).Build(resolver.Target{URL: url.URL{Scheme: Scheme, Path: "/_health._tcp"}}, cc, resolver.BuildOptions{})
+	if err != nil {
+		t.Fatalf("Build() failed: %v", err)
+	}
+	defer r.Close()
+
+	expect := func(addresses int) resolver.State {
+		t.Helper()
+
+		select {
+		case state := <-cc.states:
+			if len(state.Addresses) != addresses || len(state.Endpoints) != addresses {
+				t.Fatalf("received %+v, expected %d addresses", state, addresses)
+			}
+			return state
+		case <-time.After(5 * time.Second):
+			t.Fatalf("no state with %d addresses", addresses)
+		}
+
+		return resolver.State{}
+	}
+
+	// The initial state is pushed even though nothing was found
+	expect(0)
+
+	first := testService("First", netip.MustParseAddrPort("127.0.0.1:1001"))
+	d.AddService(first)
+
+	state := expect(1)
+	if state.Addresses[0].Addr != "127.0.0.1:1001" {
+		t.Fatalf("received address %s", state.Addresses[0].Addr)
+	}
+	if version, _ := Txt(state.Addresses[0].Attributes).GetString("version"); version != "1" {
+		t.Fatalf("received TXT version %q", version)
+	}
+	if service, ok := Service(state.Endpoints[0].Attributes); !ok || service.Name != "First" {
+		t.Fatalf("received endpoint service %+v", service)
+	}
+
+	d.AddService(testService("Second", netip.MustParseAddrPort("127.0.0.1:1002")))
+	expect(2)
+
+	d.RemoveService(first)
+	expect(1)
+}
+
+func TestResolverErrors(t *testing.T) {
+	d, s := avahitest.NewServer(t)
+
+	cc := newTestClientConn()
+	cc.updateErr = errors.New("state rejected")
+
+	r, err := NewBuilder(s).Build(resolver.Target{URL: url.URL{Scheme: Scheme, Path: "/_health._tcp"}}, cc, resolver.BuildOptions{})
+	if err != nil {
+		t.Fatalf("Build() failed: %v", err)
+	}
+	defer r.Close()
+
+	d.AddService(testService("First", netip.MustParseAddrPort("127.0.0.1:1001")))
+	cc.expectError(t, cc.updateErr)
+
+	d.FailBrowsers(avahi.ErrNoMemory)
+	cc.expectError(t, avahi.ErrNoMemory)
+}
+
+func TestDial(t *testing.T) {
+	d, s := avahitest.NewServer(t)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen() failed: %v", err)
+	}
+
+	server := grpc.NewServer()
+	healthpb.RegisterHealthServer(server, health.NewServer())
+	go server.Serve(listener)
+	defer server.Stop()
+
+	d.AddService(testService("Health", netip.MustParseAddrPort(listener.Addr().String())))
+
+	conn, err := grpc.NewClient("avahi:///_health._tcp",
+		grpc.WithResolvers(NewBuilder(s)),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("NewClient() failed: %v", err)
+	}
+	defer conn.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	resp, err := healthpb.NewHealthClient(conn).Check(ctx, &healthpb.HealthCheckRequest{}, grpc.WaitForReady(true))
+	if err != nil || resp.Status != healthpb.HealthCheckResponse_SERVING {
+		t.Fatalf("Check() returned %v, %v", resp, err)
+	}
+}
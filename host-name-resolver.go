@@ -10,7 +10,12 @@ import (
 type HostNameResolver struct {
 	object       dbus.BusObject
 	FoundChannel chan HostName
-	closeCh      chan struct{}
+
+	// FailureChannel receives the error reported by the daemon when resolving failed.
+	FailureChannel chan error
+
+	closeCh chan struct{}
+	queue   *signalQueue
 }
 
 // HostNameResolverNew returns a new HostNameResolver
@@ -20,6 +25,8 @@ func HostNameResolverNew(conn *dbus.Conn, path dbus.ObjectPath) (*HostNameResolv
 	c.object = conn.Object("org.freedesktop.Avahi", path)
 	c.FoundChannel = make(chan HostName)
 	c.closeCh = make(chan struct{})
+	c.queue = newSignalQueue()
+	c.FailureChannel = make(chan error, 1)
 
 	return c, nil
 }
@@ -35,11 +42,39 @@ func (c *HostNameResolver) free() {
 	c.object.Call(c.interfaceForMember("Free"), 0)
 }
 
+// Dropped returns the number of signals discarded because the queue was full
+func (c *HostNameResolver) Dropped() uint64 {
+	return c.queue.droppedCount()
+}
+
+func (c *HostNameResolver) getSignalQueue() *signalQueue {
+	return c.queue
+}
+
 func (c *HostNameResolver) getObjectPath() dbus.ObjectPath {
 	return c.object.Path()
 }
 
+func (c *HostNameResolver) restore(conn *dbus.Conn, path dbus.ObjectPath) error {
+	c.object = conn.Object("org.freedesktop.Avahi", path)
+	return nil
+}
+
 func (c *HostNameResolver) dispatchSignal(signal *dbus.Signal) error {
+	if signal.Name == c.interfaceForMember("Failure") {
+		var e string
+		err := dbus.Store(signal.Body, &e)
+		if err != nil {
+			return err
+		}
+
+		select {
+		case c.FailureChannel <- avahiErrorFromString(e):
+		default:
+		}
+		return nil
+	}
+
 	if signal.Name == c.interfaceForMember("Found") {
 		var hostName HostName
 		err := dbus.Store(signal.Body, &hostName.Interface, &hostName.Protocol,
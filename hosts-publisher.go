@@ -0,0 +1,229 @@
+package avahi
+
+import (
+	"fmt"
+	"sync"
+)
+
+// A HostsPublisher publishes the entries of a file in the format of /etc/avahi/hosts
+// in a single entry group, and keeps the group in sync with the file as it changes.
+// Like a Publisher, it withdraws the entries while the server is registering or has
+// a host name collision, and publishes them again once the server is running.
+type HostsPublisher struct {
+	server  *Server
+	group   *EntryGroup
+	watcher *serverWatcher
+	file    *fileWatcher
+	path    string
+	flags   uint32
+
+	// StateChangeChannel receives the state changes of the entry group.
+	// Events are dropped if the channel is not drained.
+	StateChangeChannel chan EntryGroupState
+	// ErrorChannel receives errors reading the file or publishing its entries.
+	// Errors are dropped if the channel is not drained.
+	ErrorChannel chan error
+
+	mutex     sync.Mutex
+	entries   []HostsEntry
+	published bool
+
+	quitChannel chan struct{}
+	doneChannel chan struct{}
+}
+
+// HostsPublisherNew creates a HostsPublisher for the file at path, whose entries are published with flags.
+// A missing file has no entries. Errors in the file and entries the daemon rejects are reported on
+// ErrorChannel, and the remaining entries are published nevertheless.
+func (c *Server) HostsPublisherNew(path string, flags uint32) (*HostsPublisher, error) {
+	file, err := newFileWatcher(path)
+	if err != nil {
+		return nil, err
+	}
+
+	g, err := c.EntryGroupNew()
+	if err != nil {
+		file.close()
+		return nil, err
+	}
+
+	p := new(HostsPublisher)
+	p.server = c
+	p.group = g
+	p.file = file
+	p.path = path
+	p.flags = flags
+	p.StateChangeChannel = make(chan EntryGroupState, 10)
+	p.ErrorChannel = make(chan error, 10)
+	p.quitChannel = make(chan struct{})
+	p.doneChannel = make(chan struct{})
+
+	// Watch before querying the state, so a change in between is not missed
+	p.watcher = c.watch()
+
+	p.mutex.Lock()
+	p.reload()
+
+	state, err := c.GetState()
+	if err == nil && state == ServerRunning {
+		p.report(p.publish())
+	}
+	p.mutex.Unlock()
+
+	go p.run()
+
+	return p, nil
+}
+
+// HostsPublisherFree stops watching the file and frees the entry group, which withdraws all entries
+func (c *Server) HostsPublisherFree(p *HostsPublisher) {
+	close(p.quitChannel)
+	<-p.doneChannel
+
+	p.free()
+}
+
+// Entries returns the entries last read from the file
+func (p *HostsPublisher) Entries() []HostsEntry {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	return append([]HostsEntry(nil), p.entries...)
+}
+
+func (p *HostsPublisher) free() {
+	p.file.close()
+	p.server.unwatch(p.watcher)
+	p.server.EntryGroupFree(p.group)
+}
+
+func (p *HostsPublisher) run() {
+	defer close(p.doneChannel)
+
+	for {
+		select {
+		case <-p.file.changes:
+			p.fileChanged()
+
+		case state := <-p.group.StateChangeChannel:
+			select {
+			case p.StateChangeChannel <- state:
+			default:
+			}
+
+		case state := <-p.watcher.stateChannel:
+			p.serverStateChanged(state)
+
+		case state := <-p.watcher.daemonChannel:
+			if state.State == DaemonRestored {
+				p.daemonRestored()
+			}
+
+		case <-p.quitChannel:
+			return
+		}
+	}
+}
+
+func (p *HostsPublisher) fileChanged() {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	p.reload()
+
+	if p.published {
+		p.report(p.publish())
+	}
+}
+
+func (p *HostsPublisher) serverStateChanged(state ServerState) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	switch state.State {
+	case ServerRegistering, ServerCollision:
+		p.report(p.withdraw())
+	case ServerRunning:
+		if !p.published {
+			p.report(p.publish())
+		}
+	}
+}
+
+// daemonRestored publishes the entries again if the entry group could not be restored as it was
+func (p *HostsPublisher) daemonRestored() {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if !p.published {
+		return
+	}
+
+	state, err := p.group.GetState()
+	if err == nil && state != EntryGroupUncommited && state != EntryGroupFailure {
+		return
+	}
+
+	p.report(p.withdraw())
+	p.report(p.publish())
+}
+
+// reload reads the file. The caller must hold p.mutex.
+func (p *HostsPublisher) reload() {
+	entries, err := readHosts(p.path)
+	p.report(err)
+
+	p.entries = entries
+}
+
+// publish registers the entries again. Entries the daemon rejects are reported and
+// left out, so they do not keep the others from being published. The caller must hold p.mutex.
+func (p *HostsPublisher) publish() error {
+	p.published = true
+
+	err := p.group.Reset()
+	if err != nil {
+		return err
+	}
+
+	added := false
+
+	for _, a := range HostsSpec(p.entries, p.flags).Addresses {
+		err = p.group.AddAddress(a.Interface, a.Protocol, a.Flags, a.Name, a.Address)
+		if err != nil {
+			p.report(fmt.Errorf("address %s of %q: %w", a.Address, a.Name, err))
+			continue
+		}
+
+		added = true
+	}
+
+	if !added {
+		return nil
+	}
+
+	return p.group.Commit()
+}
+
+// withdraw resets the group. The caller must hold p.mutex.
+func (p *HostsPublisher) withdraw() error {
+	if !p.published {
+		return nil
+	}
+
+	p.published = false
+
+	return p.group.Reset()
+}
+
+// report delivers err, if any. The caller must hold p.mutex.
+func (p *HostsPublisher) report(err error) {
+	if err == nil {
+		return
+	}
+
+	select {
+	case p.ErrorChannel <- err:
+	default:
+	}
+}
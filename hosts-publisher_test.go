@@ -0,0 +1,78 @@
+package avahi_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/holoplot/go-avahi"
+	"github.com/holoplot/go-avahi/avahitest"
+)
+
+func TestHostsPublisher(t *testing.T) {
+	_, s := avahitest.NewServer(t)
+
+	path := filepath.Join(t.TempDir(), "hosts")
+
+	write := func(data string) {
+		// Replace the file like editors do
+		if err := os.WriteFile(path+".tmp", []byte(data), 0o644); err != nil {
+			t.Fatalf("WriteFile() failed: %v", err)
+		}
+		if err := os.Rename(path+".tmp", path); err != nil {
+			t.Fatalf("Rename() failed: %v", err)
+		}
+	}
+
+	write("192.168.1.20 printer.local\ninvalid scanner.local\n192.168.1.21 bad..local\n")
+
+	p, err := s.HostsPublisherNew(path, 0)
+	if err != nil {
+		t.Fatalf("HostsPublisherNew() failed: %v", err)
+	}
+	defer s.HostsPublisherFree(p)
+
+	for _, expected := range []error{avahi.ErrInvalidAddress, avahi.ErrInvalidHostName} {
+		select {
+		case err := <-p.ErrorChannel:
+			if !errors.Is(err, expected) {
+				t.Fatalf("ErrorChannel delivered %v, expected %v", err, expected)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("no error, expected %v", expected)
+		}
+	}
+
+	h, err := s.ResolveHostName(avahi.InterfaceUnspec, avahi.ProtoUnspec, "printer.local", avahi.ProtoUnspec, 0)
+	if err != nil || h.Address != "192.168.1.20" {
+		t.Fatalf("ResolveHostName() returned %+v, %v", h, err)
+	}
+
+	write("192.168.1.30 scanner.local\n")
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		h, err = s.ResolveHostName(avahi.InterfaceUnspec, avahi.ProtoUnspec, "scanner.local", avahi.ProtoUnspec, 0)
+		if err == nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("scanner.local was not published: %v", err)
+		}
+	}
+
+	if h.Address != "192.168.1.30" {
+		t.Fatalf("ResolveHostName() returned %+v", h)
+	}
+
+	_, err = s.ResolveHostName(avahi.InterfaceUnspec, avahi.ProtoUnspec, "printer.local", avahi.ProtoUnspec, 0)
+	if !errors.Is(err, avahi.ErrTimeout) {
+		t.Fatalf("ResolveHostName() for a removed entry returned %v", err)
+	}
+
+	if entries := p.Entries(); len(entries) != 1 || entries[0].Name != "scanner.local" {
+		t.Fatalf("Entries() returned %+v", entries)
+	}
+}
@@ -0,0 +1,79 @@
+package avahi
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"unsafe"
+)
+
+// A fileWatcher sends on changes whenever a file is written, replaced or removed.
+// It watches the directory, so it sees files that are replaced by renaming.
+type fileWatcher struct {
+	inotify *os.File
+	name    string
+	changes chan struct{}
+}
+
+func newFileWatcher(path string) (*fileWatcher, error) {
+	fd, err := syscall.InotifyInit1(syscall.IN_CLOEXEC | syscall.IN_NONBLOCK)
+	if err != nil {
+		return nil, os.NewSyscallError("inotify_init1", err)
+	}
+
+	mask := uint32(syscall.IN_CLOSE_WRITE | syscall.IN_MOVED_TO | syscall.IN_MOVED_FROM | syscall.IN_DELETE)
+
+	_, err = syscall.InotifyAddWatch(fd, filepath.Dir(path), mask)
+	if err != nil {
+		syscall.Close(fd)
+		return nil, os.NewSyscallError("inotify_add_watch", err)
+	}
+
+	w := &fileWatcher{
+		// The descriptor is non-blocking, so reads go through the runtime poller and are interrupted by close
+		inotify: os.NewFile(uintptr(fd), "inotify"),
+		name:    filepath.Base(path),
+		changes: make(chan struct{}, 1),
+	}
+
+	go w.run()
+
+	return w, nil
+}
+
+func (w *fileWatcher) run() {
+	buf := make([]byte, 64*(syscall.SizeofInotifyEvent+syscall.NAME_MAX+1))
+
+	for {
+		n, err := w.inotify.Read(buf)
+		if err != nil {
+			return
+		}
+
+		changed := false
+
+		for offset := 0; offset+syscall.SizeofInotifyEvent <= n; {
+			event := (*syscall.InotifyEvent)(unsafe.Pointer(&buf[offset]))
+			start := offset + syscall.SizeofInotifyEvent
+			end := start + int(event.Len)
+
+			if end <= n && strings.TrimRight(string(buf[start:end]), "\x00") == w.name {
+				changed = true
+			}
+
+			offset = end
+		}
+
+		if changed {
+			select {
+			case w.changes <- struct{}{}:
+			default:
+			}
+		}
+	}
+}
+
+func (w *fileWatcher) close() {
+	w.inotify.Close()
+}
@@ -0,0 +1,64 @@
+//go:build !linux
+
+package avahi
+
+import (
+	"os"
+	"time"
+)
+
+// A fileWatcher sends on changes whenever a file is written, replaced or removed.
+// Without inotify, it compares the modification time every two seconds.
+type fileWatcher struct {
+	path    string
+	changes chan struct{}
+	quit    chan struct{}
+}
+
+func newFileWatcher(path string) (*fileWatcher, error) {
+	w := &fileWatcher{
+		path:    path,
+		changes: make(chan struct{}, 1),
+		quit:    make(chan struct{}),
+	}
+
+	go w.run()
+
+	return w, nil
+}
+
+func (w *fileWatcher) modTime() time.Time {
+	info, err := os.Stat(w.path)
+	if err != nil {
+		return time.Time{}
+	}
+
+	return info.ModTime()
+}
+
+func (w *fileWatcher) run() {
+	ticker := time.NewTicker(2 * time.Second)
+	defer ticker.Stop()
+
+	last := w.modTime()
+
+	for {
+		select {
+		case <-ticker.C:
+			if t := w.modTime(); !t.Equal(last) {
+				last = t
+
+				select {
+				case w.changes <- struct{}{}:
+				default:
+				}
+			}
+		case <-w.quit:
+			return
+		}
+	}
+}
+
+func (w *fileWatcher) close() {
+	close(w.quit)
+}
@@ -0,0 +1,106 @@
+package avahi
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strings"
+)
+
+// A HostsEntry maps an address to a host name, as a line of /etc/avahi/hosts does
+type HostsEntry struct {
+	Address string
+	Name    string
+}
+
+// ParseHosts reads a file in the format of /etc/avahi/hosts, see avahi.hosts(5):
+// every line holds an address followed by a host name, and '#' starts a comment.
+// Invalid lines are skipped. All valid entries are returned together with an
+// error describing the first invalid line, if any.
+func ParseHosts(r io.Reader) ([]HostsEntry, error) {
+	var entries []HostsEntry
+	var firstErr error
+
+	scanner := bufio.NewScanner(r)
+
+	for line := 1; scanner.Scan(); line++ {
+		text := scanner.Text()
+		if i := strings.IndexByte(text, '#'); i >= 0 {
+			text = text[:i]
+		}
+
+		fields := strings.Fields(text)
+		if len(fields) == 0 {
+			continue
+		}
+
+		var err error
+
+		switch {
+		case len(fields) != 2:
+			err = fmt.Errorf("line %d: expected an address and a host name", line)
+		case net.ParseIP(fields[0]) == nil:
+			err = fmt.Errorf("line %d: %w: %q", line, ErrInvalidAddress, fields[0])
+		}
+
+		if err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+
+		entries = append(entries, HostsEntry{Address: fields[0], Name: fields[1]})
+	}
+
+	if err := scanner.Err(); err != nil {
+		return entries, err
+	}
+
+	return entries, firstErr
+}
+
+// readHosts parses the hosts file at path. A missing file has no entries.
+func readHosts(path string) ([]HostsEntry, error) {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return ParseHosts(f)
+}
+
+// HostsSpec converts hosts entries to the addresses of an EntryGroupSpec, published with flags.
+// Only the first name of every address gets a reverse entry, as the PTR records of
+// further names would collide with it. All entries get PublishNoReverse if flags has it set.
+func HostsSpec(entries []HostsEntry, flags uint32) EntryGroupSpec {
+	var spec EntryGroupSpec
+
+	reverse := make(map[string]bool)
+
+	for _, e := range entries {
+		f := flags
+
+		address := net.ParseIP(e.Address).String()
+		if reverse[address] {
+			f |= PublishNoReverse
+		}
+		reverse[address] = true
+
+		spec.Addresses = append(spec.Addresses, AddressSpec{
+			Interface: InterfaceUnspec,
+			Protocol:  ProtoUnspec,
+			Flags:     f,
+			Name:      e.Name,
+			Address:   e.Address,
+		})
+	}
+
+	return spec
+}
@@ -0,0 +1,47 @@
+package avahi
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseHosts(t *testing.T) {
+	entries, err := ParseHosts(strings.NewReader(`# See avahi.hosts(5)
+192.168.1.20  printer.local
+192.168.1.20	scanner.local # same device
+
+fe80::20 printer.local
+invalid name.local
+192.168.1.30
+`))
+
+	expected := []HostsEntry{
+		{"192.168.1.20", "printer.local"},
+		{"192.168.1.20", "scanner.local"},
+		{"fe80::20", "printer.local"},
+	}
+
+	if !reflect.DeepEqual(entries, expected) {
+		t.Fatalf("ParseHosts() returned %+v", entries)
+	}
+
+	if !errors.Is(err, ErrInvalidAddress) || !strings.HasPrefix(err.Error(), "line 6:") {
+		t.Fatalf("ParseHosts() returned error %v", err)
+	}
+
+	spec := HostsSpec(entries, 0)
+	for i, flags := range []uint32{0, PublishNoReverse, 0} {
+		if spec.Addresses[i].Flags != flags {
+			t.Errorf("address %d has flags %d", i, spec.Addresses[i].Flags)
+		}
+	}
+
+	spec = HostsSpec(entries, PublishNoReverse)
+	for i, a := range spec.Addresses {
+		if a.Flags != PublishNoReverse {
+			t.Errorf("address %d has flags %d", i, a.Flags)
+		}
+	}
+}
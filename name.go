@@ -0,0 +1,297 @@
+package avahi
+
+import (
+	"fmt"
+	"strings"
+)
+
+// EscapeLabel escapes a raw DNS label like avahi_escape_label: dots and backslashes
+// are prefixed by a backslash, everything except letters, digits, '-' and '_'
+// is written as a backslash followed by three decimal digits, e.g. "My\032Printer".
+func EscapeLabel(label string) string {
+	var b strings.Builder
+
+	for i := 0; i < len(label); i++ {
+		ch := label[i]
+
+		switch {
+		case ch == '.' || ch == '\\':
+			b.WriteByte('\\')
+			b.WriteByte(ch)
+		case ch == '-' || ch == '_' ||
+			(ch >= '0' && ch <= '9') ||
+			(ch >= 'a' && ch <= 'z') ||
+			(ch >= 'A' && ch <= 'Z'):
+			b.WriteByte(ch)
+		default:
+			fmt.Fprintf(&b, "\\%03d", ch)
+		}
+	}
+
+	return b.String()
+}
+
+// UnescapeLabel reads the first label of the escaped name like avahi_unescape_label,
+// and returns it together with the remainder of the name after the separating dot.
+// Decimal escapes must have three digits and a value from 1 to 255.
+func UnescapeLabel(name string) (string, string, error) {
+	var b strings.Builder
+
+	for i := 0; i < len(name); i++ {
+		ch := name[i]
+
+		switch ch {
+		case '.':
+			return b.String(), name[i+1:], nil
+
+		case '\\':
+			i++
+			if i >= len(name) {
+				return "", "", fmt.Errorf("invalid escape sequence at end of %q", name)
+			}
+
+			if name[i] >= '0' && name[i] <= '9' {
+				if i+2 >= len(name) {
+					return "", "", fmt.Errorf("invalid escape sequence in %q", name)
+				}
+
+				n := 0
+				for _, d := range name[i : i+3] {
+					if d < '0' || d > '9' {
+						return "", "", fmt.Errorf("invalid escape sequence in %q", name)
+					}
+					n = n*10 + int(d-'0')
+				}
+
+				if n == 0 || n > 255 {
+					return "", "", fmt.Errorf("invalid escape sequence %s in %q", name[i-1:i+3], name)
+				}
+
+				b.WriteByte(byte(n))
+				i += 2
+			} else {
+				b.WriteByte(name[i])
+			}
+
+		default:
+			b.WriteByte(ch)
+		}
+	}
+
+	return b.String(), "", nil
+}
+
+// splitName splits an escaped domain name into its raw labels.
+// A trailing dot is ignored, the root name "" or "." has no labels.
+func splitName(name string) ([]string, error) {
+	if name == "." {
+		return nil, nil
+	}
+
+	var labels []string
+
+	for name != "" {
+		label, rest, err := UnescapeLabel(name)
+		if err != nil {
+			return nil, err
+		}
+
+		if label == "" {
+			return nil, fmt.Errorf("empty label in %q", name)
+		}
+
+		labels = append(labels, label)
+		name = rest
+	}
+
+	return labels, nil
+}
+
+// joinName joins raw labels to an escaped domain name
+func joinName(labels []string) string {
+	escaped := make([]string, len(labels))
+	for i, label := range labels {
+		escaped[i] = EscapeLabel(label)
+	}
+
+	return strings.Join(escaped, ".")
+}
+
+// NormalizeName returns name with all labels escaped the way Avahi escapes them
+// and without a trailing dot, like avahi_normalize_name
+func NormalizeName(name string) (string, error) {
+	labels, err := splitName(name)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrInvalidDomainName, err)
+	}
+
+	return joinName(labels), nil
+}
+
+// DomainEqual reports whether the escaped names a and b are equal, ignoring the
+// case of ASCII letters, differences in escaping and trailing dots, like avahi_domain_equal
+func DomainEqual(a, b string) bool {
+	la, err := splitName(a)
+	if err != nil {
+		return false
+	}
+
+	lb, err := splitName(b)
+	if err != nil || len(la) != len(lb) {
+		return false
+	}
+
+	for i := range la {
+		if !labelEqual(la[i], lb[i]) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// labelEqual compares raw labels, ignoring the case of ASCII letters only, as DNS does
+func labelEqual(a, b string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i := 0; i < len(a); i++ {
+		ca, cb := a[i], b[i]
+
+		if ca >= 'A' && ca <= 'Z' {
+			ca += 'a' - 'A'
+		}
+		if cb >= 'A' && cb <= 'Z' {
+			cb += 'a' - 'A'
+		}
+
+		if ca != cb {
+			return false
+		}
+	}
+
+	return true
+}
+
+// serviceTypeLabels checks that labels form a service type like _ipp._tcp
+// or a subtype like _color._sub._ipp._tcp
+func serviceTypeLabels(labels []string) bool {
+	switch len(labels) {
+	case 2:
+	case 4:
+		if !labelEqual(labels[1], "_sub") || len(labels[0]) < 2 || labels[0][0] != '_' {
+			return false
+		}
+		labels = labels[2:]
+	default:
+		return false
+	}
+
+	return serviceLabel(labels[0]) && (labelEqual(labels[1], "_tcp") || labelEqual(labels[1], "_udp"))
+}
+
+// serviceLabel checks the first label of a service type as in RFC 6763 section 7: an underscore
+// followed by 1 to 15 letters, digits and hyphens, at least one of which is a letter
+func serviceLabel(label string) bool {
+	if len(label) < 2 || len(label) > 16 || label[0] != '_' {
+		return false
+	}
+
+	letter := false
+
+	for i := 1; i < len(label); i++ {
+		c := label[i]
+
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z':
+			letter = true
+		case c >= '0' && c <= '9', c == '-':
+		default:
+			return false
+		}
+	}
+
+	return letter
+}
+
+// ServiceNameJoin builds the full name of the service instance name of serviceType in
+// domain, like avahi_service_name_join. The raw instance name is escaped, serviceType and
+// domain are normalized. Without an instance name, the result is the name of the PTR
+// records listing the instances, e.g. "_ipp._tcp.local". serviceType may be a subtype.
+// An empty domain stands for "local", the root domain "." is rejected.
+func ServiceNameJoin(name, serviceType, domain string) (string, error) {
+	if len(name) > maxLabelSize {
+		return "", fmt.Errorf("%w: %q", ErrInvalidServiceName, name)
+	}
+
+	typeLabels, err := splitName(serviceType)
+	if err != nil || !serviceTypeLabels(typeLabels) {
+		return "", fmt.Errorf("%w: %q", ErrInvalidServiceType, serviceType)
+	}
+
+	if domain == "" {
+		domain = "local"
+	}
+
+	domainLabels, err := splitName(domain)
+	if err != nil || len(domainLabels) == 0 {
+		return "", fmt.Errorf("%w: %q", ErrInvalidDomainName, domain)
+	}
+
+	var labels []string
+
+	if name != "" {
+		labels = append(labels, name)
+	}
+
+	labels = append(labels, typeLabels...)
+	labels = append(labels, domainLabels...)
+
+	return joinName(labels), nil
+}
+
+// ServiceNameSplit splits a full service name into the raw instance name, the service
+// type and the domain, like avahi_service_name_split. Names without an instance, like
+// "_ipp._tcp.local" or "_color._sub._ipp._tcp.local", return an empty instance name.
+func ServiceNameSplit(fullName string) (string, string, string, error) {
+	labels, err := splitName(fullName)
+	if err != nil {
+		return "", "", "", fmt.Errorf("%w: %v", ErrInvalidDomainName, err)
+	}
+
+	// The service type ends with the first _tcp or _udp label after the instance name
+	end := -1
+	for i := 1; i < len(labels); i++ {
+		if labelEqual(labels[i], "_tcp") || labelEqual(labels[i], "_udp") {
+			end = i + 1
+			break
+		}
+	}
+
+	if end < 0 {
+		return "", "", "", fmt.Errorf("%w: no service type in %q", ErrInvalidServiceType, fullName)
+	}
+
+	start := end - 2
+	if start >= 2 && labelEqual(labels[start-1], "_sub") {
+		start -= 2
+	}
+
+	if start > 1 || !serviceTypeLabels(labels[start:end]) {
+		return "", "", "", fmt.Errorf("%w: no service type in %q", ErrInvalidServiceType, fullName)
+	}
+
+	var name string
+	if start == 1 {
+		name = labels[0]
+	}
+
+	return name, joinName(labels[start:end]), joinName(labels[end:]), nil
+}
+
+// FullName returns the escaped full name of the service, as used by the SRV and TXT
+// records of the instance, e.g. for RecordBrowserNew
+func (s Service) FullName() (string, error) {
+	return ServiceNameJoin(s.Name, s.Type, s.Domain)
+}
@@ -0,0 +1,128 @@
+package avahi
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestEscapeLabel(t *testing.T) {
+	for raw, escaped := range map[string]string{
+		"Printer":          "Printer",
+		"My Printer":       `My\032Printer`,
+		"a.b":              `a\.b`,
+		`back\slash`:       `back\\slash`,
+		"_ipp-2":           "_ipp-2",
+		"Drucker (Büro)":   `Drucker\032\040B\195\188ro\041`,
+		"printer.local..x": `printer\.local\.\.x`,
+	} {
+		if e := EscapeLabel(raw); e != escaped {
+			t.Errorf("EscapeLabel(%q) returned %q, expected %q", raw, e, escaped)
+		}
+
+		label, rest, err := UnescapeLabel(escaped + ".local")
+		if err != nil || label != raw || rest != "local" {
+			t.Errorf("UnescapeLabel(%q) returned %q, %q, %v", escaped+".local", label, rest, err)
+		}
+	}
+
+	if e := EscapeLabel("\x00"); e != `\000` {
+		t.Errorf("EscapeLabel() of NUL returned %q", e)
+	}
+}
+
+func TestUnescapeLabel(t *testing.T) {
+	tests := []struct {
+		name, label, rest string
+		ok                bool
+	}{
+		{`My\032Printer._ipp._tcp`, "My Printer", "_ipp._tcp", true},
+		{`a\.b.local`, "a.b", "local", true},
+		{`\255`, "\xff", "", true},
+		{`\001x`, "\x01x", "", true},
+		{`a\\b.`, `a\b`, "", true},
+		{`a\`, "", "", false},
+		{`a.b\`, "a", `b\`, true},
+		{`a\25`, "", "", false},
+		{`a\2x5`, "", "", false},
+		{`a\256`, "", "", false},
+		{`a\999.local`, "", "", false},
+		{`a\000`, "", "", false},
+		{`\0`, "", "", false},
+	}
+
+	for _, test := range tests {
+		label, rest, err := UnescapeLabel(test.name)
+		if label != test.label || rest != test.rest || (err == nil) != test.ok {
+			t.Errorf("UnescapeLabel(%q) returned %q, %q, %v", test.name, label, rest, err)
+		}
+	}
+}
+
+func TestServiceNameJoinSplit(t *testing.T) {
+	tests := []struct {
+		name, serviceType, domain, full string
+	}{
+		{"My Printer", "_ipp._tcp", "local", `My\032Printer._ipp._tcp.local`},
+		{"a.b", "_http._tcp", "example.com.", `a\.b._http._tcp.example.com`},
+		{"", "_ipp._tcp", "local", "_ipp._tcp.local"},
+		{"", "_color._sub._ipp._tcp", "local", "_color._sub._ipp._tcp.local"},
+		{"_tcp", "_ipp._udp", "local", "_tcp._ipp._udp.local"},
+	}
+
+	for _, test := range tests {
+		full, err := ServiceNameJoin(test.name, test.serviceType, test.domain)
+		if err != nil || full != test.full {
+			t.Errorf("ServiceNameJoin(%q, %q, %q) returned %q, %v", test.name, test.serviceType, test.domain, full, err)
+		}
+
+		name, serviceType, domain, err := ServiceNameSplit(full)
+		if err != nil || name != test.name || serviceType != test.serviceType || !DomainEqual(domain, test.domain) {
+			t.Errorf("ServiceNameSplit(%q) returned %q, %q, %q, %v", full, name, serviceType, domain, err)
+		}
+	}
+
+	for _, test := range []struct {
+		name, serviceType, domain string
+		err                       error
+	}{
+		{"Printer", "ipp._tcp", "local", ErrInvalidServiceType},
+		{"Printer", "_ipp._sctp", "local", ErrInvalidServiceType},
+		{"Printer", "_ipp._tcp", `local\`, ErrInvalidDomainName},
+		{"Printer", "_ipp._tcp", ".", ErrInvalidDomainName},
+		{string(make([]byte, 64)), "_ipp._tcp", "local", ErrInvalidServiceName},
+	} {
+		if _, err := ServiceNameJoin(test.name, test.serviceType, test.domain); !errors.Is(err, test.err) {
+			t.Errorf("ServiceNameJoin(%q, %q, %q) returned %v", test.name, test.serviceType, test.domain, err)
+		}
+	}
+
+	if full, err := ServiceNameJoin("Printer", "_ipp._tcp", ""); err != nil || full != "Printer._ipp._tcp.local" {
+		t.Errorf("ServiceNameJoin() without domain returned %q, %v", full, err)
+	}
+
+	for _, full := range []string{"printer.local", "A.B._ipp._tcp.local", `x\`} {
+		if _, _, _, err := ServiceNameSplit(full); err == nil {
+			t.Errorf("ServiceNameSplit(%q) succeeded", full)
+		}
+	}
+
+	s := Service{Name: "My Printer", Type: "_ipp._tcp", Domain: "local"}
+	if full, err := s.FullName(); err != nil || full != `My\032Printer._ipp._tcp.local` {
+		t.Errorf("FullName() returned %q, %v", full, err)
+	}
+}
+
+func TestNormalizeName(t *testing.T) {
+	name, err := NormalizeName(`My\ Printer\._ipp._tcp.local.`)
+	if err != nil || name != `My\032Printer\._ipp._tcp.local` {
+		t.Fatalf("NormalizeName() returned %q, %v", name, err)
+	}
+
+	if !DomainEqual(`My\032PRINTER._IPP._tcp.Local.`, `my printer._ipp._tcp.local`) {
+		t.Error("DomainEqual() is false for names differing in case and escaping")
+	}
+
+	if DomainEqual("printer.local", "printer.local.example") || DomainEqual("Büro.local", "BÜRO.local") {
+		t.Error("DomainEqual() is true for different names")
+	}
+}
@@ -0,0 +1,55 @@
+package avahi_test
+
+import (
+	"net/netip"
+	"testing"
+
+	"github.com/holoplot/go-avahi"
+	"github.com/holoplot/go-avahi/avahitest"
+)
+
+func TestNetip(t *testing.T) {
+	d, s := avahitest.NewServer(t)
+
+	d.AddHost(avahi.HostName{
+		Interface: 2,
+		Protocol:  avahi.ProtoInet6,
+		Name:      "printer.local",
+		Aprotocol: avahi.ProtoInet6,
+		Address:   "fe80::20",
+		Flags:     avahi.LookupResultMulticast,
+	})
+
+	h, err := s.ResolveHostName(avahi.InterfaceUnspec, avahi.ProtoUnspec, "printer.local", avahi.ProtoInet6, 0)
+	if err != nil {
+		t.Fatalf("ResolveHostName() failed: %v", err)
+	}
+
+	addr, err := h.Addr(s)
+	if err != nil || addr != netip.MustParseAddr("fe80::20%eth0") {
+		t.Fatalf("Addr() returned %v, %v", addr, err)
+	}
+
+	a, err := s.ResolveAddr(avahi.InterfaceUnspec, avahi.ProtoUnspec, addr, 0)
+	if err != nil || a.Name != "printer.local" || a.Interface != 2 {
+		t.Fatalf("ResolveAddr() returned %+v, %v", a, err)
+	}
+
+	eg, err := s.EntryGroupNew()
+	if err != nil {
+		t.Fatalf("EntryGroupNew() failed: %v", err)
+	}
+
+	err = eg.AddAddr(avahi.InterfaceUnspec, avahi.ProtoUnspec, 0, "scanner.local", netip.MustParseAddr("fe80::30%lo"))
+	if err == nil {
+		err = eg.Commit()
+	}
+	if err != nil {
+		t.Fatalf("publishing address failed: %v", err)
+	}
+
+	a, err = s.ResolveAddress(avahi.InterfaceUnspec, avahi.ProtoUnspec, "fe80::30", 0)
+	if err != nil || a.Name != "scanner.local" || a.Interface != 1 {
+		t.Fatalf("ResolveAddress() returned %+v, %v", a, err)
+	}
+}
@@ -0,0 +1,89 @@
+package avahi
+
+import (
+	"fmt"
+	"net/netip"
+
+	dbus "github.com/godbus/dbus/v5"
+)
+
+// zonedAddr parses address and, for link-local IPv6 addresses, sets the name of
+// the interface iface as zone, so the address can be dialed. If c is nil, no zone is set.
+func zonedAddr(c *Server, address string, iface int32) (netip.Addr, error) {
+	addr, err := netip.ParseAddr(address)
+	if err != nil {
+		return netip.Addr{}, err
+	}
+
+	if c == nil || !addr.Is6() || iface < 0 ||
+		!(addr.IsLinkLocalUnicast() || addr.IsLinkLocalMulticast() || addr.IsInterfaceLocalMulticast()) {
+		return addr, nil
+	}
+
+	name, err := c.GetNetworkInterfaceNameByIndex(iface)
+	if err != nil {
+		return netip.Addr{}, fmt.Errorf("zone of %s: %w", addr, err)
+	}
+
+	return addr.WithZone(name), nil
+}
+
+// Addr returns the resolved address. Link-local IPv6 addresses get the name
+// of Interface as zone, which is looked up with c. If c is nil, no zone is set.
+func (h HostName) Addr(c *Server) (netip.Addr, error) {
+	return zonedAddr(c, h.Address, h.Interface)
+}
+
+// Addr returns the address. Link-local IPv6 addresses get the name
+// of Interface as zone, which is looked up with c. If c is nil, no zone is set.
+func (a Address) Addr(c *Server) (netip.Addr, error) {
+	return zonedAddr(c, a.Address, a.Interface)
+}
+
+// Addr returns the resolved address of the service. Link-local IPv6 addresses get
+// the name of Interface as zone, which is looked up with c. If c is nil, no zone is set.
+func (s Service) Addr(c *Server) (netip.Addr, error) {
+	return zonedAddr(c, s.Address, s.Interface)
+}
+
+// AddrPort returns the resolved address and port of the service, see Addr
+func (s Service) AddrPort(c *Server) (netip.AddrPort, error) {
+	addr, err := s.Addr(c)
+	if err != nil {
+		return netip.AddrPort{}, err
+	}
+
+	return netip.AddrPortFrom(addr, s.Port), nil
+}
+
+// interfaceOfZone returns iface, or the index of the interface named by the
+// zone of addr if iface is InterfaceUnspec
+func interfaceOfZone(conn *dbus.Conn, iface int32, addr netip.Addr) (int32, error) {
+	if iface != InterfaceUnspec || addr.Zone() == "" {
+		return iface, nil
+	}
+
+	return interfaceIndexByName(conn, addr.Zone())
+}
+
+// ResolveAddr is like ResolveAddress for a netip.Addr. If iface is InterfaceUnspec
+// and addr has a zone, the address is resolved on the interface named by the zone.
+func (c *Server) ResolveAddr(iface, protocol int32, addr netip.Addr, flags uint32) (Address, error) {
+	iface, err := interfaceOfZone(c.conn, iface, addr)
+	if err != nil {
+		return Address{}, err
+	}
+
+	return c.ResolveAddress(iface, protocol, addr.WithZone("").String(), flags)
+}
+
+// AddAddr is like AddAddress for a netip.Addr. If iface is InterfaceUnspec and
+// addr has a zone, the address is published on the interface named by the zone.
+func (c *EntryGroup) AddAddr(iface, protocol int32, flags uint32, name string, addr netip.Addr) error {
+	iface, err := interfaceOfZone(c.conn, iface, addr)
+	if err != nil {
+		return err
+	}
+
+	return c.AddAddress(iface, protocol, flags, name, addr.WithZone("").String())
+}
@@ -0,0 +1,28 @@
+package avahi
+
+import (
+	"net/netip"
+	"testing"
+)
+
+func TestServiceAddrPort(t *testing.T) {
+	s := Service{Interface: 2, Address: "fe80::1", Port: 631}
+
+	addrPort, err := s.AddrPort(nil)
+	if err != nil || addrPort != netip.MustParseAddrPort("[fe80::1]:631") {
+		t.Fatalf("AddrPort() returned %v, %v", addrPort, err)
+	}
+
+	h := HostName{Interface: 2, Address: "192.168.1.20"}
+
+	addr, err := h.Addr(nil)
+	if err != nil || addr != netip.MustParseAddr("192.168.1.20") {
+		t.Fatalf("Addr() returned %v, %v", addr, err)
+	}
+
+	a := Address{Address: "printer"}
+
+	if _, err := a.Addr(nil); err == nil {
+		t.Fatal("Addr() of an invalid address succeeded")
+	}
+}
@@ -0,0 +1,85 @@
+package avahi_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/holoplot/go-avahi"
+	"github.com/holoplot/go-avahi/avahitest"
+)
+
+var printerService = avahi.Service{
+	Interface: 2,
+	Protocol:  avahi.ProtoInet,
+	Name:      "Printer",
+	Type:      "_ipp._tcp",
+	Domain:    "local",
+	Host:      "printer.local",
+	Aprotocol: avahi.ProtoInet,
+	Address:   "192.168.1.20",
+	Port:      631,
+	Txt:       [][]byte{[]byte("txtvers=1")},
+	Flags:     avahi.LookupResultMulticast,
+}
+
+func TestOptions(t *testing.T) {
+	d, s := avahitest.NewServer(t)
+
+	other := printerService
+	other.Interface = 1
+	d.AddService(printerService)
+	d.AddService(other)
+
+	eg, err := s.EntryGroupNew()
+	if err != nil {
+		t.Fatalf("EntryGroupNew() failed: %v", err)
+	}
+
+	err = eg.AddServiceWithOptions("Web", "_http._tcp", 80, nil, avahi.WithInterfaceName("eth0"), avahi.WithProtocol(avahi.ProtoInet))
+	if err == nil {
+		err = eg.Commit()
+	}
+	if err != nil {
+		t.Fatalf("publishing failed: %v", err)
+	}
+
+	b, err := s.ServiceBrowserNewWithOptions("_ipp._tcp", avahi.WithInterfaceName("eth0"), avahi.WithDomain("local"))
+	if err != nil {
+		t.Fatalf("ServiceBrowserNewWithOptions() failed: %v", err)
+	}
+
+	select {
+	case service := <-b.AddChannel:
+		if service.Interface != 2 {
+			t.Fatalf("browser on eth0 found %+v", service)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no service found")
+	}
+
+	select {
+	case <-b.AllForNowChannel:
+	case <-time.After(5 * time.Second):
+		t.Fatal("no AllForNow")
+	}
+
+	r, err := s.ServiceResolverNewWithOptions("Web", "_http._tcp", avahi.WithAddressProtocol(avahi.ProtoInet))
+	if err != nil {
+		t.Fatalf("ServiceResolverNewWithOptions() failed: %v", err)
+	}
+
+	select {
+	case service := <-r.FoundChannel:
+		if service.Interface != 2 || service.Port != 80 {
+			t.Fatalf("resolved %+v", service)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("service not resolved")
+	}
+
+	_, err = s.ServiceBrowserNewWithOptions("_ipp._tcp", avahi.WithInterfaceName("wlan7"))
+	if !errors.Is(err, avahi.ErrOS) {
+		t.Fatalf("ServiceBrowserNewWithOptions() for an unknown interface returned %v", err)
+	}
+}
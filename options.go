@@ -0,0 +1,131 @@
+package avahi
+
+import (
+	dbus "github.com/godbus/dbus/v5"
+)
+
+// An Option configures a browser, resolver or service. Options that do not
+// apply to a call are ignored.
+type Option func(*options)
+
+type options struct {
+	iface         int32
+	ifaceName     string
+	protocol      int32
+	aprotocol     int32
+	domain        string
+	host          string
+	flags         uint32
+	queueSize     int
+	queueOverflow int32
+}
+
+// WithInterface limits browsing and resolving to, or publishes on, the network interface with the given index.
+// The default is InterfaceUnspec.
+func WithInterface(index int32) Option {
+	return func(o *options) {
+		o.iface = index
+		o.ifaceName = ""
+	}
+}
+
+// WithInterfaceName is like WithInterface, with the index looked up by name with GetNetworkInterfaceIndexByName
+func WithInterfaceName(name string) Option {
+	return func(o *options) {
+		o.ifaceName = name
+	}
+}
+
+// WithProtocol sets the protocol used for mDNS, ProtoInet or ProtoInet6. The default is ProtoUnspec.
+func WithProtocol(protocol int32) Option {
+	return func(o *options) {
+		o.protocol = protocol
+	}
+}
+
+// WithAddressProtocol sets the protocol of the addresses a resolver looks for. The default is ProtoUnspec.
+func WithAddressProtocol(protocol int32) Option {
+	return func(o *options) {
+		o.aprotocol = protocol
+	}
+}
+
+// WithDomain sets the domain. The default is the daemon's default domain.
+func WithDomain(domain string) Option {
+	return func(o *options) {
+		o.domain = domain
+	}
+}
+
+// WithHost sets the host a service is published for. The default is the local host.
+func WithHost(host string) Option {
+	return func(o *options) {
+		o.host = host
+	}
+}
+
+// WithLookupFlags sets the LookupUseWideArea, LookupUseMulticast, LookupNoTXT and LookupNoAddress flags of browsers and resolvers
+func WithLookupFlags(flags uint32) Option {
+	return func(o *options) {
+		o.flags = flags
+	}
+}
+
+// WithPublishFlags sets the Publish* flags of a service
+func WithPublishFlags(flags uint32) Option {
+	return func(o *options) {
+		o.flags = flags
+	}
+}
+
+// WithBuffer sets the size and overflow policy of the signal queue of a browser or resolver,
+// instead of those set with Server.SetQueuePolicy
+func WithBuffer(size int, overflow int32) Option {
+	return func(o *options) {
+		o.queueSize = size
+		o.queueOverflow = overflow
+	}
+}
+
+func optionsOf(opts []Option) options {
+	o := options{
+		iface:     InterfaceUnspec,
+		protocol:  ProtoUnspec,
+		aprotocol: ProtoUnspec,
+	}
+
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	return o
+}
+
+// options applies opts and looks up the interface index if it was given by name
+func (c *Server) options(opts []Option) (options, error) {
+	o := optionsOf(opts)
+
+	if o.ifaceName != "" {
+		index, err := c.GetNetworkInterfaceIndexByName(o.ifaceName)
+		if err != nil {
+			return o, err
+		}
+
+		o.iface = index
+	}
+
+	return o, nil
+}
+
+// interfaceIndexByName looks up an interface index for objects that have no Server at hand
+func interfaceIndexByName(conn *dbus.Conn, name string) (int32, error) {
+	var index int32
+
+	err := conn.Object("org.freedesktop.Avahi", "/").
+		Call("org.freedesktop.Avahi.Server.GetNetworkInterfaceIndexByName", 0, name).Store(&index)
+	if err != nil {
+		return 0, avahiError(err)
+	}
+
+	return index, nil
+}
@@ -0,0 +1,34 @@
+package avahi
+
+import "testing"
+
+func TestOptions(t *testing.T) {
+	o := optionsOf(nil)
+	if o.iface != InterfaceUnspec || o.protocol != ProtoUnspec || o.aprotocol != ProtoUnspec || o.domain != "" || o.flags != 0 {
+		t.Fatalf("default options are %+v", o)
+	}
+
+	o = optionsOf([]Option{
+		WithInterfaceName("eth0"),
+		WithInterface(3),
+		WithProtocol(ProtoInet6),
+		WithAddressProtocol(ProtoInet),
+		WithDomain("example.com"),
+		WithLookupFlags(LookupUseMulticast),
+		WithBuffer(16, QueueOverflowDropOldest),
+	})
+
+	expected := options{
+		iface:         3,
+		protocol:      ProtoInet6,
+		aprotocol:     ProtoInet,
+		domain:        "example.com",
+		flags:         LookupUseMulticast,
+		queueSize:     16,
+		queueOverflow: QueueOverflowDropOldest,
+	}
+
+	if o != expected {
+		t.Fatalf("options are %+v, expected %+v", o, expected)
+	}
+}
@@ -0,0 +1,401 @@
+package avahi
+
+import (
+	"errors"
+	"sync"
+)
+
+// A PublisherState describes the state of a service of a Publisher
+type PublisherState struct {
+	// State is the state of the underlying entry group, e.g. EntryGroupEstablished
+	State int32
+	// Name is the service name the state applies to. It is empty if the Publisher has no services.
+	Name string
+	// Alternative is the name the service is renamed to after an EntryGroupCollision
+	Alternative string
+	// Error is set for EntryGroupCollision and EntryGroupFailure only
+	Error error
+}
+
+// publisherMaxRenames limits how often a Publisher renames its services in a row on
+// local collisions, which renaming does not resolve if they are caused by addresses or records
+const publisherMaxRenames = 100
+
+type publisherServiceKey struct {
+	Name string
+	Type string
+}
+
+// A Publisher keeps a single service, or all entries of an EntryGroupSpec, published.
+// When service names collide with other services on the network, it picks alternative
+// names with GetAlternativeServiceName and registers the entries again. The entries are
+// withdrawn while the server is registering or has a host name collision, and published
+// again once the server is running, including after avahi-daemon restarted.
+type Publisher struct {
+	server  *Server
+	group   *EntryGroup
+	watcher *serverWatcher
+
+	// StateChangeChannel receives a PublisherState for every service whenever the state
+	// of the entry group changes. Events are dropped if the channel is not drained.
+	StateChangeChannel chan PublisherState
+
+	mutex sync.Mutex
+	spec  EntryGroupSpec
+	// names holds the names in use for services of spec that were renamed
+	names     map[publisherServiceKey]string
+	published bool
+
+	quitChannel chan struct{}
+	doneChannel chan struct{}
+}
+
+// PublisherNew creates a Publisher for a service. The arguments are the same as for EntryGroup.AddService.
+// If the server is running, the service is added and committed before PublisherNew returns, otherwise
+// as soon as the server is running.
+func (c *Server) PublisherNew(iface, protocol int32, flags uint32, name, serviceType, domain, host string, port uint16, txt [][]byte) (*Publisher, error) {
+	return c.PublisherNewSpec(EntryGroupSpec{
+		Services: []ServiceSpec{{
+			Interface: iface,
+			Protocol:  protocol,
+			Flags:     flags,
+			Name:      name,
+			Type:      serviceType,
+			Domain:    domain,
+			Host:      host,
+			Port:      port,
+			Txt:       txt,
+		}},
+	})
+}
+
+// PublisherNewSpec creates a Publisher for all entries of spec, which is validated first.
+// If the server is running, the entries are published before PublisherNewSpec returns,
+// otherwise as soon as the server is running. avahi-daemon does not tell which name of
+// a group collided, so on every collision all services are renamed, each reported in a
+// separate EntryGroupCollision state.
+func (c *Server) PublisherNewSpec(spec EntryGroupSpec) (*Publisher, error) {
+	err := spec.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	g, err := c.EntryGroupNew()
+	if err != nil {
+		return nil, err
+	}
+
+	p := new(Publisher)
+	p.server = c
+	p.group = g
+	p.StateChangeChannel = make(chan PublisherState, 10)
+	p.spec = spec.clone()
+	p.names = make(map[publisherServiceKey]string)
+	p.quitChannel = make(chan struct{})
+	p.doneChannel = make(chan struct{})
+
+	// Watch before querying the state, so a change in between is not missed
+	p.watcher = c.watch()
+
+	state, err := c.GetState()
+	if err == nil && state == ServerRunning {
+		p.mutex.Lock()
+		err = p.publish()
+		p.mutex.Unlock()
+	}
+
+	if err != nil {
+		close(p.doneChannel)
+		p.free()
+		return nil, err
+	}
+
+	go p.run()
+
+	return p, nil
+}
+
+// PublisherFree withdraws the entries of a Publisher and frees its entry group
+func (c *Server) PublisherFree(p *Publisher) {
+	close(p.quitChannel)
+	<-p.doneChannel
+
+	p.free()
+}
+
+// Name returns the name currently in use for the first service, which differs
+// from the requested name after collisions
+func (p *Publisher) Name() string {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	spec := p.effective()
+	if len(spec.Services) == 0 {
+		return ""
+	}
+
+	return spec.Services[0].Name
+}
+
+// Spec returns the entries currently published, with the service names in use
+func (p *Publisher) Spec() EntryGroupSpec {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	return p.effective()
+}
+
+// UpdateTxt replaces the TXT record of the first service
+func (p *Publisher) UpdateTxt(txt [][]byte) error {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if len(p.spec.Services) == 0 {
+		return nil
+	}
+
+	spec := p.spec.clone()
+	spec.Services[0].Txt = txt
+
+	return p.update(spec)
+}
+
+// UpdateSpec replaces the entries of the Publisher. Services keep the names they were
+// renamed to. If only TXT records changed, they are updated in place.
+func (p *Publisher) UpdateSpec(spec EntryGroupSpec) error {
+	err := spec.Validate()
+	if err != nil {
+		return err
+	}
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	return p.update(spec)
+}
+
+func (p *Publisher) free() {
+	p.server.unwatch(p.watcher)
+	p.server.EntryGroupFree(p.group)
+}
+
+func (p *Publisher) run() {
+	defer close(p.doneChannel)
+
+	for {
+		select {
+		case state := <-p.group.StateChangeChannel:
+			p.entryGroupStateChanged(state)
+
+		case state := <-p.watcher.stateChannel:
+			p.serverStateChanged(state)
+
+		case state := <-p.watcher.daemonChannel:
+			if state.State == DaemonRestored {
+				p.daemonRestored()
+			}
+
+		case <-p.quitChannel:
+			return
+		}
+	}
+}
+
+func (p *Publisher) entryGroupStateChanged(state EntryGroupState) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if state.State != EntryGroupCollision {
+		var err error
+		if state.State == EntryGroupFailure {
+			err = state.Err()
+		}
+
+		p.reportAll(state.State, err)
+		return
+	}
+
+	// Without services, there is no name to change
+	if len(p.spec.Services) == 0 {
+		p.reportAll(state.State, state.Err())
+		return
+	}
+
+	err := p.rename(state.Err())
+	if err == nil {
+		err = p.publish()
+	}
+
+	if err != nil {
+		p.reportAll(EntryGroupFailure, err)
+	}
+}
+
+func (p *Publisher) serverStateChanged(state ServerState) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	var err error
+
+	switch state.State {
+	case ServerRegistering, ServerCollision:
+		err = p.withdraw()
+	case ServerRunning:
+		if !p.published {
+			err = p.publish()
+		}
+	}
+
+	if err != nil {
+		p.reportAll(EntryGroupFailure, err)
+	}
+}
+
+// daemonRestored publishes the entries again if the entry group could not be
+// restored as it was
+func (p *Publisher) daemonRestored() {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if !p.published {
+		return
+	}
+
+	state, err := p.group.GetState()
+	if err == nil && state != EntryGroupUncommited && state != EntryGroupFailure {
+		return
+	}
+
+	err = p.publish()
+	if err != nil {
+		p.reportAll(EntryGroupFailure, err)
+	}
+}
+
+// effective returns the spec with the service names in use. The caller must hold p.mutex.
+func (p *Publisher) effective() EntryGroupSpec {
+	spec := p.spec.clone()
+
+	for i, s := range spec.Services {
+		if name, ok := p.names[publisherServiceKey{s.Name, s.Type}]; ok {
+			spec.Services[i].Name = name
+		}
+	}
+
+	return spec
+}
+
+// update replaces the spec, keeping the names of renamed services, and updates the
+// group if it is published. The caller must hold p.mutex.
+func (p *Publisher) update(spec EntryGroupSpec) error {
+	names := make(map[publisherServiceKey]string)
+	for _, s := range spec.Services {
+		key := publisherServiceKey{s.Name, s.Type}
+		if name, ok := p.names[key]; ok {
+			names[key] = name
+		}
+	}
+
+	p.spec = spec.clone()
+	p.names = names
+
+	if !p.published {
+		return nil
+	}
+
+	err := p.group.UpdateSpec(p.effective())
+	if errors.Is(err, ErrCollision) {
+		return p.publish()
+	}
+	if err != nil {
+		p.published = false
+	}
+
+	return err
+}
+
+// publish replaces the contents of the group with the spec and commits it, renaming
+// the services on local collisions. The caller must hold p.mutex.
+func (p *Publisher) publish() error {
+	p.published = false
+
+	for i := 0; ; i++ {
+		err := p.group.ApplySpec(p.effective())
+		if !errors.Is(err, ErrCollision) || len(p.spec.Services) == 0 || i == publisherMaxRenames {
+			p.published = err == nil
+			return err
+		}
+
+		err = p.rename(err)
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// withdraw resets the group. The caller must hold p.mutex.
+func (p *Publisher) withdraw() error {
+	if !p.published {
+		return nil
+	}
+
+	err := p.group.Reset()
+	if err != nil {
+		return err
+	}
+
+	p.published = false
+
+	return nil
+}
+
+// rename switches all services to alternative names and reports the collision
+// with cause. The caller must hold p.mutex.
+func (p *Publisher) rename(cause error) error {
+	renamed := make(map[publisherServiceKey]bool)
+
+	for _, s := range p.spec.Services {
+		key := publisherServiceKey{s.Name, s.Type}
+		if renamed[key] {
+			continue
+		}
+
+		current, ok := p.names[key]
+		if !ok {
+			current = s.Name
+		}
+
+		name, err := p.server.GetAlternativeServiceName(current)
+		if err != nil {
+			return err
+		}
+
+		p.names[key] = name
+		renamed[key] = true
+
+		p.report(PublisherState{State: EntryGroupCollision, Name: current, Alternative: name, Error: cause})
+	}
+
+	return nil
+}
+
+// reportAll delivers a state change for every service. The caller must hold p.mutex.
+func (p *Publisher) reportAll(state int32, err error) {
+	spec := p.effective()
+	if len(spec.Services) == 0 {
+		p.report(PublisherState{State: state, Error: err})
+		return
+	}
+
+	for _, s := range spec.Services {
+		p.report(PublisherState{State: state, Name: s.Name, Error: err})
+	}
+}
+
+// report delivers a state change. The caller must hold p.mutex.
+func (p *Publisher) report(state PublisherState) {
+	select {
+	case p.StateChangeChannel <- state:
+	default:
+	}
+}
@@ -0,0 +1,132 @@
+package avahi_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/holoplot/go-avahi"
+	"github.com/holoplot/go-avahi/avahitest"
+)
+
+func TestPublisher(t *testing.T) {
+	d, s := avahitest.NewServer(t)
+
+	d.AddCollision("Printer")
+
+	p, err := s.PublisherNew(avahi.InterfaceUnspec, avahi.ProtoUnspec, 0, "Printer", "_ipp._tcp", "", "", 631, nil)
+	if err != nil {
+		t.Fatalf("PublisherNew() failed: %v", err)
+	}
+	defer s.PublisherFree(p)
+
+	established := func() {
+		t.Helper()
+
+		for {
+			select {
+			case state := <-p.StateChangeChannel:
+				if state.State == avahi.EntryGroupEstablished {
+					if state.Name != "Printer #2" || state.Error != nil {
+						t.Fatalf("established as %q, with error %v", state.Name, state.Error)
+					}
+					return
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatal("service not established")
+			}
+		}
+	}
+
+	established()
+
+	if p.Name() != "Printer #2" {
+		t.Fatalf("Name() returned %q", p.Name())
+	}
+
+	d.SetState(avahi.ServerCollision, nil)
+
+	deadline := time.Now().Add(5 * time.Second)
+	for len(d.Services()) != 0 {
+		if time.Now().After(deadline) {
+			t.Fatalf("service not withdrawn: %+v", d.Services())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	d.SetState(avahi.ServerRunning, nil)
+	established()
+
+	if services := d.Services(); len(services) != 1 || services[0].Name != "Printer #2" {
+		t.Fatalf("services after server collision: %+v", services)
+	}
+}
+
+func TestPublisherSpec(t *testing.T) {
+	d, s := avahitest.NewServer(t)
+
+	d.AddCollision("Printer")
+
+	spec := avahi.EntryGroupSpec{
+		Services: []avahi.ServiceSpec{
+			{Interface: avahi.InterfaceUnspec, Protocol: avahi.ProtoUnspec, Name: "Printer", Type: "_ipp._tcp", Port: 631},
+			{Interface: avahi.InterfaceUnspec, Protocol: avahi.ProtoUnspec, Name: "Scanner", Type: "_scanner._tcp", Port: 6566},
+		},
+	}
+
+	p, err := s.PublisherNewSpec(spec)
+	if err != nil {
+		t.Fatalf("PublisherNewSpec() failed: %v", err)
+	}
+	defer s.PublisherFree(p)
+
+	renamed := map[string]string{}
+	established := map[string]bool{}
+
+	for len(established) < 2 {
+		select {
+		case state := <-p.StateChangeChannel:
+			switch state.State {
+			case avahi.EntryGroupCollision:
+				if !errors.Is(state.Error, avahi.ErrCollision) {
+					t.Fatalf("collision of %q reported with error %v", state.Name, state.Error)
+				}
+				renamed[state.Name] = state.Alternative
+			case avahi.EntryGroupEstablished:
+				established[state.Name] = true
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("services not established: %v", established)
+		}
+	}
+
+	if renamed["Printer"] != "Printer #2" || !established["Printer #2"] || !established["Scanner #2"] {
+		t.Fatalf("renamed %v, established %v", renamed, established)
+	}
+
+	spec.Services[0].Txt = [][]byte{[]byte("txtvers=2")}
+
+	err = p.UpdateSpec(spec)
+	if err != nil {
+		t.Fatalf("UpdateSpec() failed: %v", err)
+	}
+
+	if names := p.Spec().Services; names[0].Name != "Printer #2" || names[1].Name != "Scanner #2" {
+		t.Fatalf("Spec() returned %+v", names)
+	}
+
+	updated := false
+	for _, service := range d.Services() {
+		if service.Name == "Printer #2" {
+			updated = len(service.Txt) == 1 && string(service.Txt[0]) == "txtvers=2"
+		}
+	}
+	if !updated {
+		t.Fatalf("TXT record not updated: %+v", d.Services())
+	}
+
+	spec.Services[0].Type = "ipp"
+	if err := p.UpdateSpec(spec); !errors.Is(err, avahi.ErrInvalidServiceType) {
+		t.Fatalf("UpdateSpec() of an invalid spec returned %v", err)
+	}
+}
@@ -0,0 +1,458 @@
+package avahi
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"sort"
+)
+
+const (
+	// DNSClassIN - The Internet class
+	DNSClassIN = 1
+)
+
+const (
+	// DNSTypeA - IPv4 host address
+	DNSTypeA = 1
+	// DNSTypeNS - Authoritative name server
+	DNSTypeNS = 2
+	// DNSTypeCNAME - Canonical name for an alias
+	DNSTypeCNAME = 5
+	// DNSTypeSOA - Start of a zone of authority
+	DNSTypeSOA = 6
+	// DNSTypePTR - Domain name pointer
+	DNSTypePTR = 12
+	// DNSTypeHINFO - Host information
+	DNSTypeHINFO = 13
+	// DNSTypeMX - Mail exchange
+	DNSTypeMX = 15
+	// DNSTypeTXT - Text strings
+	DNSTypeTXT = 16
+	// DNSTypeAAAA - IPv6 host address
+	DNSTypeAAAA = 28
+	// DNSTypeSRV - Service location
+	DNSTypeSRV = 33
+	// DNSTypeNSEC - Next secure record, used by mDNS for negative responses
+	DNSTypeNSEC = 47
+	// DNSTypeANY - Any type, only valid in queries
+	DNSTypeANY = 255
+)
+
+const (
+	maxLabelSize = 63
+	maxNameSize  = 255
+)
+
+// ErrRdataTruncated is returned when record data ends before a complete record has been decoded
+var ErrRdataTruncated = errors.New("truncated record data")
+
+// An Rdata is the decoded data of a DNS resource record
+type Rdata interface {
+	// RecordType returns the DNS type of the record, e.g. DNSTypeA
+	RecordType() uint16
+	// Encode returns the record data in DNS wire format, suitable for EntryGroup.AddRecord
+	Encode() ([]byte, error)
+}
+
+// RdataA is the data of an A record
+type RdataA struct {
+	Address net.IP
+}
+
+// RdataAAAA is the data of an AAAA record
+type RdataAAAA struct {
+	Address net.IP
+}
+
+// RdataPTR is the data of a PTR record
+type RdataPTR struct {
+	Name string
+}
+
+// RdataCNAME is the data of a CNAME record
+type RdataCNAME struct {
+	Name string
+}
+
+// RdataSRV is the data of an SRV record
+type RdataSRV struct {
+	Priority uint16
+	Weight   uint16
+	Port     uint16
+	Target   string
+}
+
+// RdataTXT is the data of a TXT record. Use ParseTxt to decode DNS-SD attributes.
+type RdataTXT struct {
+	Txt [][]byte
+}
+
+// RdataHINFO is the data of an HINFO record
+type RdataHINFO struct {
+	CPU string
+	OS  string
+}
+
+// RdataNSEC is the data of an NSEC record
+type RdataNSEC struct {
+	NextDomain string
+	Types      []uint16
+}
+
+// RdataUnknown holds the undecoded data of records of any other type
+type RdataUnknown struct {
+	Type uint16
+	Data []byte
+}
+
+// RecordType returns DNSTypeA
+func (r RdataA) RecordType() uint16 { return DNSTypeA }
+
+// RecordType returns DNSTypeAAAA
+func (r RdataAAAA) RecordType() uint16 { return DNSTypeAAAA }
+
+// RecordType returns DNSTypePTR
+func (r RdataPTR) RecordType() uint16 { return DNSTypePTR }
+
+// RecordType returns DNSTypeCNAME
+func (r RdataCNAME) RecordType() uint16 { return DNSTypeCNAME }
+
+// RecordType returns DNSTypeSRV
+func (r RdataSRV) RecordType() uint16 { return DNSTypeSRV }
+
+// RecordType returns DNSTypeTXT
+func (r RdataTXT) RecordType() uint16 { return DNSTypeTXT }
+
+// RecordType returns DNSTypeHINFO
+func (r RdataHINFO) RecordType() uint16 { return DNSTypeHINFO }
+
+// RecordType returns DNSTypeNSEC
+func (r RdataNSEC) RecordType() uint16 { return DNSTypeNSEC }
+
+// RecordType returns the type the data belongs to
+func (r RdataUnknown) RecordType() uint16 { return r.Type }
+
+// Decode decodes the data of the record according to its type
+func (r Record) Decode() (Rdata, error) {
+	return DecodeRdata(r.Type, r.Rdata)
+}
+
+// DecodeRdata decodes record data in DNS wire format. Data of types without
+// a specific decoder is returned as RdataUnknown.
+func DecodeRdata(recordType uint16, data []byte) (Rdata, error) {
+	d := rdataDecoder{data: data}
+
+	var r Rdata
+
+	switch recordType {
+	case DNSTypeA:
+		r = RdataA{Address: net.IP(d.bytes(net.IPv4len))}
+	case DNSTypeAAAA:
+		r = RdataAAAA{Address: net.IP(d.bytes(net.IPv6len))}
+	case DNSTypePTR:
+		r = RdataPTR{Name: d.name()}
+	case DNSTypeCNAME:
+		r = RdataCNAME{Name: d.name()}
+	case DNSTypeSRV:
+		r = RdataSRV{Priority: d.uint16(), Weight: d.uint16(), Port: d.uint16(), Target: d.name()}
+	case DNSTypeTXT:
+		txt := [][]byte{}
+		for d.err == nil && d.offset < len(d.data) {
+			txt = append(txt, d.characterString())
+		}
+		r = RdataTXT{Txt: txt}
+	case DNSTypeHINFO:
+		r = RdataHINFO{CPU: string(d.characterString()), OS: string(d.characterString())}
+	case DNSTypeNSEC:
+		r = RdataNSEC{NextDomain: d.name(), Types: d.typeBitmaps()}
+	default:
+		return RdataUnknown{Type: recordType, Data: append([]byte{}, data...)}, nil
+	}
+
+	if d.err != nil {
+		return nil, d.err
+	}
+
+	if d.offset != len(d.data) {
+		return nil, fmt.Errorf("%d trailing bytes after record data of type %d", len(d.data)-d.offset, recordType)
+	}
+
+	return r, nil
+}
+
+type rdataDecoder struct {
+	data   []byte
+	offset int
+	err    error
+}
+
+func (d *rdataDecoder) bytes(n int) []byte {
+	if d.err != nil {
+		return nil
+	}
+
+	if d.offset+n > len(d.data) {
+		d.err = ErrRdataTruncated
+		return nil
+	}
+
+	b := append([]byte{}, d.data[d.offset:d.offset+n]...)
+	d.offset += n
+
+	return b
+}
+
+func (d *rdataDecoder) uint16() uint16 {
+	b := d.bytes(2)
+	if b == nil {
+		return 0
+	}
+
+	return binary.BigEndian.Uint16(b)
+}
+
+func (d *rdataDecoder) characterString() []byte {
+	b := d.bytes(1)
+	if b == nil {
+		return nil
+	}
+
+	return d.bytes(int(b[0]))
+}
+
+// name decodes a domain name. Compression pointers are followed as long as
+// each of them points before all data visited so far.
+func (d *rdataDecoder) name() string {
+	if d.err != nil {
+		return ""
+	}
+
+	var labels []string
+
+	offset := d.offset
+	limit := d.offset
+	end := -1
+	size := 0
+
+	for {
+		if offset >= len(d.data) {
+			d.err = ErrRdataTruncated
+			return ""
+		}
+
+		n := int(d.data[offset])
+
+		switch n & 0xc0 {
+		case 0x00:
+			if n == 0 {
+				if end < 0 {
+					end = offset + 1
+				}
+				d.offset = end
+				return joinName(labels)
+			}
+
+			if offset+1+n > len(d.data) {
+				d.err = ErrRdataTruncated
+				return ""
+			}
+
+			size += n + 1
+			if size > maxNameSize {
+				d.err = fmt.Errorf("domain name exceeds %d bytes", maxNameSize)
+				return ""
+			}
+
+			labels = append(labels, string(d.data[offset+1:offset+1+n]))
+			offset += 1 + n
+
+		case 0xc0:
+			if offset+1 >= len(d.data) {
+				d.err = ErrRdataTruncated
+				return ""
+			}
+
+			pointer := int(binary.BigEndian.Uint16(d.data[offset:]) & 0x3fff)
+			if pointer >= limit {
+				d.err = fmt.Errorf("invalid compression pointer %d at offset %d", pointer, offset)
+				return ""
+			}
+
+			if end < 0 {
+				end = offset + 2
+			}
+			offset = pointer
+			limit = pointer
+
+		default:
+			d.err = fmt.Errorf("invalid label type 0x%02x at offset %d", n&0xc0, offset)
+			return ""
+		}
+	}
+}
+
+func (d *rdataDecoder) typeBitmaps() []uint16 {
+	var types []uint16
+
+	for d.err == nil && d.offset < len(d.data) {
+		header := d.bytes(2)
+		if header == nil {
+			break
+		}
+
+		window, length := header[0], int(header[1])
+		if length < 1 || length > 32 {
+			d.err = fmt.Errorf("invalid NSEC bitmap length %d", length)
+			break
+		}
+
+		bitmap := d.bytes(length)
+
+		for i, b := range bitmap {
+			for bit := 0; bit < 8; bit++ {
+				if b&(0x80>>bit) != 0 {
+					types = append(types, uint16(window)<<8|uint16(i*8+bit))
+				}
+			}
+		}
+	}
+
+	return types
+}
+
+func encodeName(b []byte, name string) ([]byte, error) {
+	labels, err := splitName(name)
+	if err != nil {
+		return nil, err
+	}
+
+	size := 1
+	for _, label := range labels {
+		if len(label) > maxLabelSize {
+			return nil, fmt.Errorf("label %q exceeds %d bytes", label, maxLabelSize)
+		}
+
+		size += len(label) + 1
+		if size > maxNameSize {
+			return nil, fmt.Errorf("domain name %q exceeds %d bytes", name, maxNameSize)
+		}
+
+		b = append(b, byte(len(label)))
+		b = append(b, label...)
+	}
+
+	return append(b, 0), nil
+}
+
+func encodeCharacterString(b []byte, s []byte) ([]byte, error) {
+	if len(s) > 255 {
+		return nil, fmt.Errorf("character string exceeds 255 bytes")
+	}
+
+	b = append(b, byte(len(s)))
+	return append(b, s...), nil
+}
+
+// Encode returns the address in wire format
+func (r RdataA) Encode() ([]byte, error) {
+	ip := r.Address.To4()
+	if ip == nil {
+		return nil, fmt.Errorf("%v is not an IPv4 address", r.Address)
+	}
+
+	return append([]byte{}, ip...), nil
+}
+
+// Encode returns the address in wire format
+func (r RdataAAAA) Encode() ([]byte, error) {
+	ip := r.Address.To16()
+	if ip == nil || r.Address.To4() != nil {
+		return nil, fmt.Errorf("%v is not an IPv6 address", r.Address)
+	}
+
+	return append([]byte{}, ip...), nil
+}
+
+// Encode returns the name in wire format
+func (r RdataPTR) Encode() ([]byte, error) {
+	return encodeName(nil, r.Name)
+}
+
+// Encode returns the name in wire format
+func (r RdataCNAME) Encode() ([]byte, error) {
+	return encodeName(nil, r.Name)
+}
+
+// Encode returns the service location in wire format
+func (r RdataSRV) Encode() ([]byte, error) {
+	b := make([]byte, 6)
+	binary.BigEndian.PutUint16(b[0:], r.Priority)
+	binary.BigEndian.PutUint16(b[2:], r.Weight)
+	binary.BigEndian.PutUint16(b[4:], r.Port)
+
+	return encodeName(b, r.Target)
+}
+
+// Encode returns the strings in wire format. An empty record is encoded as a single empty string.
+func (r RdataTXT) Encode() ([]byte, error) {
+	if len(r.Txt) == 0 {
+		return []byte{0}, nil
+	}
+
+	var b []byte
+	var err error
+
+	for _, s := range r.Txt {
+		b, err = encodeCharacterString(b, s)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return b, nil
+}
+
+// Encode returns the host information in wire format
+func (r RdataHINFO) Encode() ([]byte, error) {
+	b, err := encodeCharacterString(nil, []byte(r.CPU))
+	if err != nil {
+		return nil, err
+	}
+
+	return encodeCharacterString(b, []byte(r.OS))
+}
+
+// Encode returns the next domain name and type bitmaps in wire format
+func (r RdataNSEC) Encode() ([]byte, error) {
+	b, err := encodeName(nil, r.NextDomain)
+	if err != nil {
+		return nil, err
+	}
+
+	types := append([]uint16{}, r.Types...)
+	sort.Slice(types, func(i, j int) bool { return types[i] < types[j] })
+
+	for i := 0; i < len(types); {
+		window := byte(types[i] >> 8)
+		var bitmap [32]byte
+		length := 0
+
+		for ; i < len(types) && byte(types[i]>>8) == window; i++ {
+			n := int(types[i] & 0xff)
+			bitmap[n/8] |= 0x80 >> (n % 8)
+			length = n/8 + 1
+		}
+
+		b = append(b, window, byte(length))
+		b = append(b, bitmap[:length]...)
+	}
+
+	return b, nil
+}
+
+// Encode returns the data as is
+func (r RdataUnknown) Encode() ([]byte, error) {
+	return append([]byte{}, r.Data...), nil
+}
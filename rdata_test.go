@@ -0,0 +1,77 @@
+package avahi
+
+import (
+	"bytes"
+	"errors"
+	"net"
+	"reflect"
+	"testing"
+)
+
+func TestRdataRoundTrip(t *testing.T) {
+	for _, rdata := range []Rdata{
+		RdataA{Address: net.IPv4(192, 168, 1, 10).To4()},
+		RdataAAAA{Address: net.ParseIP("fe80::1")},
+		RdataPTR{Name: `My\032Printer._ipp._tcp.local`},
+		RdataCNAME{Name: "host.local"},
+		RdataSRV{Priority: 0, Weight: 0, Port: 631, Target: "host.local"},
+		RdataTXT{Txt: [][]byte{[]byte("txtvers=1"), []byte("secure")}},
+		RdataHINFO{CPU: "X86_64", OS: "LINUX"},
+		RdataNSEC{NextDomain: "host.local", Types: []uint16{DNSTypeA, DNSTypeAAAA, DNSTypeNSEC, 1234}},
+		RdataUnknown{Type: DNSTypeMX, Data: []byte{1, 2, 3}},
+	} {
+		b, err := rdata.Encode()
+		if err != nil {
+			t.Fatalf("%T.Encode() failed: %v", rdata, err)
+		}
+
+		decoded, err := Record{Type: rdata.RecordType(), Rdata: b}.Decode()
+		if err != nil {
+			t.Fatalf("Decode() of %T failed: %v", rdata, err)
+		}
+
+		if !reflect.DeepEqual(decoded, rdata) {
+			t.Fatalf("Decode() returned %#v, expected %#v", decoded, rdata)
+		}
+	}
+}
+
+func TestRdataWireFormat(t *testing.T) {
+	b, err := RdataSRV{Priority: 1, Weight: 2, Port: 80, Target: "a.local"}.Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []byte{0, 1, 0, 2, 0, 80, 1, 'a', 5, 'l', 'o', 'c', 'a', 'l', 0}
+	if !bytes.Equal(b, expected) {
+		t.Fatalf("Encode() returned %v, expected %v", b, expected)
+	}
+
+	b, err = RdataTXT{}.Encode()
+	if err != nil || !bytes.Equal(b, []byte{0}) {
+		t.Fatalf("Encode() of empty TXT returned %v, %v", b, err)
+	}
+}
+
+func TestRdataNameCompression(t *testing.T) {
+	// A name may point back to an earlier name inside the same record data
+	data := []byte{5, 'l', 'o', 'c', 'a', 'l', 0, 1, 'a', 0xc0, 0}
+
+	d := rdataDecoder{data: data}
+	if name := d.name(); name != "local" {
+		t.Fatalf("name() returned %q", name)
+	}
+	if name := d.name(); name != "a.local" || d.offset != len(data) {
+		t.Fatalf("name() returned %q, offset %d", name, d.offset)
+	}
+
+	d = rdataDecoder{data: []byte{1, 'a', 0xc0, 0}}
+	if d.name(); d.err == nil {
+		t.Fatal("name() accepted a compression loop")
+	}
+
+	_, err := DecodeRdata(DNSTypeSRV, []byte{0, 1, 0})
+	if !errors.Is(err, ErrRdataTruncated) {
+		t.Fatalf("DecodeRdata() returned %v, expected %v", err, ErrRdataTruncated)
+	}
+}
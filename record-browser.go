@@ -11,7 +11,17 @@ type RecordBrowser struct {
 	object        dbus.BusObject
 	AddChannel    chan Record
 	RemoveChannel chan Record
-	closeCh       chan struct{}
+
+	// AllForNowChannel receives a value once the initial cache dump is complete
+	// and no further items are expected in the near future.
+	AllForNowChannel chan struct{}
+	// CacheExhaustedChannel receives a value once all entries from the daemon's cache have been delivered.
+	CacheExhaustedChannel chan struct{}
+	// FailureChannel receives the error reported by the daemon when browsing failed.
+	FailureChannel chan error
+
+	closeCh chan struct{}
+	queue   *signalQueue
 }
 
 // RecordBrowserNew creates a new mDNS record browser
@@ -22,6 +32,10 @@ func RecordBrowserNew(conn *dbus.Conn, path dbus.ObjectPath) (*RecordBrowser, er
 	c.AddChannel = make(chan Record)
 	c.RemoveChannel = make(chan Record)
 	c.closeCh = make(chan struct{})
+	c.queue = newSignalQueue()
+	c.AllForNowChannel = make(chan struct{}, 1)
+	c.CacheExhaustedChannel = make(chan struct{}, 1)
+	c.FailureChannel = make(chan error, 1)
 
 	return c, nil
 }
@@ -37,11 +51,54 @@ func (c *RecordBrowser) free() {
 	c.object.Call(c.interfaceForMember("Free"), 0)
 }
 
+// Dropped returns the number of signals discarded because the queue was full
+func (c *RecordBrowser) Dropped() uint64 {
+	return c.queue.droppedCount()
+}
+
+func (c *RecordBrowser) getSignalQueue() *signalQueue {
+	return c.queue
+}
+
 func (c *RecordBrowser) getObjectPath() dbus.ObjectPath {
 	return c.object.Path()
 }
 
+func (c *RecordBrowser) restore(conn *dbus.Conn, path dbus.ObjectPath) error {
+	c.object = conn.Object("org.freedesktop.Avahi", path)
+	return nil
+}
+
 func (c *RecordBrowser) dispatchSignal(signal *dbus.Signal) error {
+	switch signal.Name {
+	case c.interfaceForMember("AllForNow"):
+		select {
+		case c.AllForNowChannel <- struct{}{}:
+		default:
+		}
+		return nil
+
+	case c.interfaceForMember("CacheExhausted"):
+		select {
+		case c.CacheExhaustedChannel <- struct{}{}:
+		default:
+		}
+		return nil
+
+	case c.interfaceForMember("Failure"):
+		var e string
+		err := dbus.Store(signal.Body, &e)
+		if err != nil {
+			return err
+		}
+
+		select {
+		case c.FailureChannel <- avahiErrorFromString(e):
+		default:
+		}
+		return nil
+	}
+
 	if signal.Name == c.interfaceForMember("ItemNew") || signal.Name == c.interfaceForMember("ItemRemove") {
 		var record Record
 		err := dbus.Store(signal.Body, &record.Interface, &record.Protocol, &record.Name,
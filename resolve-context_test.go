@@ -0,0 +1,69 @@
+package avahi_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/holoplot/go-avahi"
+	"github.com/holoplot/go-avahi/avahitest"
+)
+
+func TestResolveContext(t *testing.T) {
+	d, s := avahitest.NewServer(t)
+
+	d.AddHost(avahi.HostName{
+		Interface: 2,
+		Protocol:  avahi.ProtoInet,
+		Name:      "printer.local",
+		Aprotocol: avahi.ProtoInet,
+		Address:   "192.168.1.20",
+	})
+	d.AddService(avahi.Service{
+		Interface: 2,
+		Protocol:  avahi.ProtoInet,
+		Name:      "Printer",
+		Type:      "_ipp._tcp",
+		Domain:    "local",
+		Host:      "printer.local",
+		Aprotocol: avahi.ProtoInet,
+		Address:   "192.168.1.20",
+		Port:      631,
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	h, err := s.ResolveHostNameContext(ctx, avahi.InterfaceUnspec, avahi.ProtoUnspec, "printer.local", avahi.ProtoUnspec, 0)
+	if err != nil || h.Address != "192.168.1.20" {
+		t.Fatalf("ResolveHostNameContext() returned %+v, %v", h, err)
+	}
+
+	a, err := s.ResolveAddressContext(ctx, avahi.InterfaceUnspec, avahi.ProtoUnspec, "192.168.1.20", 0)
+	if err != nil || a.Name != "printer.local" {
+		t.Fatalf("ResolveAddressContext() returned %+v, %v", a, err)
+	}
+
+	service, err := s.ResolveServiceContext(ctx, avahi.InterfaceUnspec, avahi.ProtoUnspec, "Printer", "_ipp._tcp", "local", avahi.ProtoUnspec, 0)
+	if err != nil || service.Port != 631 {
+		t.Fatalf("ResolveServiceContext() returned %+v, %v", service, err)
+	}
+
+	// The daemon gives up first
+	_, err = s.ResolveServiceContext(ctx, avahi.InterfaceUnspec, avahi.ProtoUnspec, "Missing", "_ipp._tcp", "local", avahi.ProtoUnspec, 0)
+	if err == nil || errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("ResolveServiceContext() of a missing service returned %v", err)
+	}
+
+	// The context is done first
+	d.SetResolveTimeout(time.Minute)
+
+	short, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	_, err = s.ResolveServiceContext(short, avahi.InterfaceUnspec, avahi.ProtoUnspec, "Missing", "_ipp._tcp", "local", avahi.ProtoUnspec, 0)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("ResolveServiceContext() with an expired context returned %v", err)
+	}
+}
@@ -0,0 +1,69 @@
+package avahi_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/holoplot/go-avahi"
+	"github.com/holoplot/go-avahi/avahitest"
+)
+
+func TestRestart(t *testing.T) {
+	d, s := avahitest.NewServer(t)
+
+	eg, err := s.EntryGroupNew()
+	if err != nil {
+		t.Fatalf("EntryGroupNew() failed: %v", err)
+	}
+
+	err = eg.AddService(avahi.InterfaceUnspec, avahi.ProtoUnspec, 0, "Web", "_http._tcp", "", "", 80, nil)
+	if err == nil {
+		err = eg.Commit()
+	}
+	if err != nil {
+		t.Fatalf("publishing failed: %v", err)
+	}
+
+	b, err := s.ServiceBrowserNew(avahi.InterfaceUnspec, avahi.ProtoUnspec, "_ipp._tcp", "local", 0)
+	if err != nil {
+		t.Fatalf("ServiceBrowserNew() failed: %v", err)
+	}
+
+	err = d.Restart()
+	if err != nil {
+		t.Fatalf("Restart() failed: %v", err)
+	}
+
+	for _, expected := range []int32{avahi.DaemonLost, avahi.DaemonRestored} {
+		select {
+		case state := <-s.DaemonStateChannel:
+			if state.State != expected || state.Error != nil {
+				t.Fatalf("DaemonStateChannel delivered %+v, expected state %d", state, expected)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("no daemon state change")
+		}
+	}
+
+	services := d.Services()
+	if len(services) != 1 || services[0].Name != "Web" {
+		t.Fatalf("services after restart: %+v", services)
+	}
+
+	d.AddService(avahi.Service{
+		Interface: 2,
+		Protocol:  avahi.ProtoInet,
+		Name:      "Printer",
+		Type:      "_ipp._tcp",
+		Domain:    "local",
+	})
+
+	select {
+	case service := <-b.AddChannel:
+		if service.Name != "Printer" {
+			t.Fatalf("browser found %+v after restart", service)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("browser was not re-created")
+	}
+}
@@ -0,0 +1,36 @@
+package avahi_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/holoplot/go-avahi"
+	"github.com/holoplot/go-avahi/avahitest"
+)
+
+func TestServerStateChanged(t *testing.T) {
+	d, s := avahitest.NewServer(t)
+
+	d.SetState(avahi.ServerCollision, nil)
+
+	select {
+	case state := <-s.StateChangeChannel:
+		if state.State != avahi.ServerCollision || state.Error != avahi.ErrCollision.Name || !errors.Is(state.Err(), avahi.ErrCollision) {
+			t.Fatalf("StateChangeChannel delivered %+v", state)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no server state change")
+	}
+
+	d.SetState(avahi.ServerRunning, nil)
+
+	select {
+	case state := <-s.StateChangeChannel:
+		if state.State != avahi.ServerRunning || state.Error != "org.freedesktop.Avahi.Success" || state.Err() != nil {
+			t.Fatalf("StateChangeChannel delivered %+v", state)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no server state change")
+	}
+}
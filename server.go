@@ -1,7 +1,9 @@
 package avahi
 
 import (
+	"context"
 	"fmt"
+	"strings"
 	"sync"
 
 	dbus "github.com/godbus/dbus/v5"
@@ -20,15 +22,73 @@ const (
 	ServerFailure = 4
 )
 
+const (
+	// DaemonLost - avahi-daemon has left the bus, all browsers, resolvers and entry groups are gone
+	DaemonLost = 0
+	// DaemonRestored - avahi-daemon is back on the bus and all browsers, resolvers and entry groups have been re-created
+	DaemonRestored = 1
+)
+
+// A DaemonState describes a change in the availability of avahi-daemon on the bus.
+// Error is set if some objects could not be re-created after a restart.
+type DaemonState struct {
+	State int32
+	Error error
+}
+
+// A ServerState describes the current state of the Avahi server.
+// Error carries the daemon's error string for ServerCollision and ServerFailure.
+type ServerState struct {
+	State int32
+	Error string
+}
+
+// Err returns Error as an *Error, or nil if it reports no error
+func (s ServerState) Err() error {
+	return avahiErrorFromString(s.Error)
+}
+
 // A Server is the cental object of an Avahi connection
 type Server struct {
 	conn          *dbus.Conn
 	object        dbus.BusObject
 	signalChannel chan *dbus.Signal
 	quitChannel   chan struct{}
+	doneChannel   chan struct{}
+	closeOnce     sync.Once
 
-	mutex          sync.Mutex
-	signalEmitters map[dbus.ObjectPath]signalEmitter
+	// StateChangeChannel receives a ServerState whenever the daemon emits
+	// StateChanged, e.g. on a host name collision. Events are dropped if the
+	// channel is not drained.
+	StateChangeChannel chan ServerState
+
+	// DaemonStateChannel receives a DaemonState whenever avahi-daemon leaves
+	// or re-joins the bus. Events are dropped if the channel is not drained.
+	DaemonStateChannel chan DaemonState
+
+	mutex                sync.Mutex
+	signalEmitters       map[dbus.ObjectPath]signalEmitter
+	signalEmitterSources map[signalEmitter]signalEmitterSource
+	server2              bool
+	server2Checked       bool
+	queueSize            int
+	queueOverflow        int32
+
+	watchMutex sync.Mutex
+	watchers   map[*serverWatcher]struct{}
+}
+
+// A serverWatcher receives server and daemon state changes for helpers that
+// must not consume StateChangeChannel and DaemonStateChannel.
+type serverWatcher struct {
+	stateChannel  chan ServerState
+	daemonChannel chan DaemonState
+}
+
+// serverMatchRules are the match rules for the signals of the server itself
+var serverMatchRules = []string{
+	"type='signal',sender='org.freedesktop.Avahi',path='/',interface='org.freedesktop.Avahi.Server',member='StateChanged'",
+	"type='signal',sender='org.freedesktop.DBus',interface='org.freedesktop.DBus',member='NameOwnerChanged',arg0='org.freedesktop.Avahi'",
 }
 
 // ServerNew returns a new Server object
@@ -38,13 +98,24 @@ func ServerNew(conn *dbus.Conn) (*Server, error) {
 	c.object = conn.Object("org.freedesktop.Avahi", dbus.ObjectPath("/"))
 	c.signalChannel = make(chan *dbus.Signal, 10)
 	c.quitChannel = make(chan struct{})
+	c.doneChannel = make(chan struct{})
+	c.StateChangeChannel = make(chan ServerState, 10)
+	c.DaemonStateChannel = make(chan DaemonState, 10)
 
 	c.conn.Signal(c.signalChannel)
-	c.conn.BusObject().Call("org.freedesktop.DBus.AddMatch", 0, "type='signal',interface='org.freedesktop.Avahi'")
+	for _, rule := range serverMatchRules {
+		c.conn.BusObject().Call("org.freedesktop.DBus.AddMatch", 0, rule)
+	}
 
 	c.signalEmitters = make(map[dbus.ObjectPath]signalEmitter)
+	c.signalEmitterSources = make(map[signalEmitter]signalEmitterSource)
+	c.watchers = make(map[*serverWatcher]struct{})
+	c.queueSize = DefaultQueueSize
+	c.queueOverflow = QueueOverflowUnbounded
 
 	go func() {
+		defer close(c.doneChannel)
+
 		for {
 			select {
 			case signal, ok := <-c.signalChannel:
@@ -52,14 +123,24 @@ func ServerNew(conn *dbus.Conn) (*Server, error) {
 					continue
 				}
 
-				c.mutex.Lock()
-				for path, obj := range c.signalEmitters {
-					if path == signal.Path {
-						_ = obj.dispatchSignal(signal)
-					}
+				if signal.Name == "org.freedesktop.DBus.NameOwnerChanged" {
+					c.nameOwnerChanged(signal)
+					continue
+				}
+
+				if signal.Path == c.object.Path() && signal.Name == c.interfaceForMember("StateChanged") {
+					c.stateChanged(signal)
+					continue
 				}
+
+				c.mutex.Lock()
+				obj, ok := c.signalEmitters[signal.Path]
 				c.mutex.Unlock()
 
+				if ok {
+					obj.getSignalQueue().push(signal)
+				}
+
 			case <-c.quitChannel:
 				return
 			}
@@ -69,43 +150,160 @@ func ServerNew(conn *dbus.Conn) (*Server, error) {
 	return c, nil
 }
 
-// Close closes the connection to a server
+// Close closes the connection to a server. Closing a server again has no effect.
 func (c *Server) Close() {
-	c.quitChannel <- struct{}{}
+	c.closeOnce.Do(func() {
+		// Closing the queues first releases the signal goroutine if it waits for room in a full queue
+		c.mutex.Lock()
+
+		for obj := range c.signalEmitterSources {
+			c.signalEmitterUnmatch(obj.getObjectPath())
+			obj.getSignalQueue().close()
+			obj.free()
+		}
 
-	c.mutex.Lock()
-	defer c.mutex.Unlock()
+		c.signalEmitters = make(map[dbus.ObjectPath]signalEmitter)
+		c.signalEmitterSources = make(map[signalEmitter]signalEmitterSource)
+
+		c.mutex.Unlock()
+
+		for _, rule := range serverMatchRules {
+			c.conn.BusObject().Call("org.freedesktop.DBus.RemoveMatch", 0, rule)
+		}
+
+		close(c.quitChannel)
+	})
+
+	<-c.doneChannel
+}
+
+func (c *Server) stateChanged(signal *dbus.Signal) {
+	var state ServerState
+
+	err := dbus.Store(signal.Body, &state.State, &state.Error)
+	if err != nil {
+		return
+	}
 
-	for path, obj := range c.signalEmitters {
-		obj.free()
-		delete(c.signalEmitters, path)
+	select {
+	case c.StateChangeChannel <- state:
+	default:
 	}
+
+	c.watchMutex.Lock()
+	for w := range c.watchers {
+		select {
+		case w.stateChannel <- state:
+		default:
+		}
+	}
+	c.watchMutex.Unlock()
+}
+
+func (c *Server) nameOwnerChanged(signal *dbus.Signal) {
+	var name, oldOwner, newOwner string
+
+	err := dbus.Store(signal.Body, &name, &oldOwner, &newOwner)
+	if err != nil || name != "org.freedesktop.Avahi" {
+		return
+	}
+
+	var state DaemonState
+
+	if newOwner == "" {
+		state.State = DaemonLost
+	} else {
+		c.mutex.Lock()
+		c.server2Checked = false
+		state.State = DaemonRestored
+		state.Error = c.signalEmittersRestore()
+		c.mutex.Unlock()
+	}
+
+	select {
+	case c.DaemonStateChannel <- state:
+	default:
+	}
+
+	c.watchMutex.Lock()
+	for w := range c.watchers {
+		select {
+		case w.daemonChannel <- state:
+		default:
+		}
+	}
+	c.watchMutex.Unlock()
+}
+
+// watch returns a serverWatcher that receives all state changes until it is
+// passed to unwatch. Events are dropped if its channels are not drained.
+func (c *Server) watch() *serverWatcher {
+	w := &serverWatcher{
+		stateChannel:  make(chan ServerState, 10),
+		daemonChannel: make(chan DaemonState, 10),
+	}
+
+	c.watchMutex.Lock()
+	c.watchers[w] = struct{}{}
+	c.watchMutex.Unlock()
+
+	return w
+}
+
+func (c *Server) unwatch(w *serverWatcher) {
+	c.watchMutex.Lock()
+	delete(c.watchers, w)
+	c.watchMutex.Unlock()
 }
 
 func (c *Server) interfaceForMember(method string) string {
 	return fmt.Sprintf("%s.%s", "org.freedesktop.Avahi.Server", method)
 }
 
-// EntryGroupNew returns a new and empty EntryGroup
-func (c *Server) EntryGroupNew() (*EntryGroup, error) {
-	var o dbus.ObjectPath
+// hasServer2 reports whether the daemon implements org.freedesktop.Avahi.Server2,
+// which was added in Avahi 0.8. The caller must hold c.mutex.
+func (c *Server) hasServer2() bool {
+	if c.server2Checked {
+		return c.server2
+	}
 
-	c.mutex.Lock()
-	defer c.mutex.Unlock()
+	var xml string
 
-	err := c.object.Call(c.interfaceForMember("EntryGroupNew"), 0).Store(&o)
+	err := c.object.Call("org.freedesktop.DBus.Introspectable.Introspect", 0).Store(&xml)
 	if err != nil {
-		return nil, err
+		return false
 	}
 
-	r, err := EntryGroupNew(c.conn, o)
+	c.server2 = strings.Contains(xml, `"org.freedesktop.Avahi.Server2"`)
+	c.server2Checked = true
+
+	return c.server2
+}
+
+// SetQueuePolicy sets the size and overflow policy of the signal queues of all browsers, resolvers
+// and entry groups created afterwards. Every object has its own queue, from which signals are
+// delivered to its channels. The default is QueueOverflowUnbounded, so a slow consumer never
+// stalls the delivery of signals to other objects.
+func (c *Server) SetQueuePolicy(size int, overflow int32) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.queueSize = size
+	c.queueOverflow = overflow
+}
+
+// EntryGroupNew returns a new and empty EntryGroup
+func (c *Server) EntryGroupNew() (*EntryGroup, error) {
+	source := signalEmitterSource{objectType: "EntryGroup"}
+
+	e, err := c.signalEmitterNew(context.Background(), source, func(path dbus.ObjectPath) (signalEmitter, error) {
+		return EntryGroupNew(c.conn, path)
+	})
 	if err != nil {
 		return nil, err
 	}
 
-	c.signalEmitters[o] = r
-
-	return r, nil
+	return e.(*EntryGroup), nil
 }
 
 // EntryGroupFree frees an entry group and releases its resources on the service
@@ -117,14 +315,14 @@ func (c *Server) EntryGroupFree(r *EntryGroup) {
 func (c *Server) ResolveHostName(iface, protocol int32, name string, aprotocol int32, flags uint32) (reply HostName, err error) {
 	err = c.object.Call(c.interfaceForMember("ResolveHostName"), 0, iface, protocol, name, aprotocol, flags).
 		Store(&reply.Interface, &reply.Protocol, &reply.Name, &reply.Aprotocol, &reply.Address, &reply.Flags)
-	return reply, err
+	return reply, avahiError(err)
 }
 
 // ResolveAddress ...
 func (c *Server) ResolveAddress(iface, protocol int32, address string, flags uint32) (reply Address, err error) {
 	err = c.object.Call(c.interfaceForMember("ResolveAddress"), 0, iface, protocol, address, flags).
 		Store(&reply.Interface, &reply.Protocol, &reply.Aprotocol, &reply.Address, &reply.Name, &reply.Flags)
-	return reply, err
+	return reply, avahiError(err)
 }
 
 // ResolveService ...
@@ -132,24 +330,93 @@ func (c *Server) ResolveService(iface, protocol int32, name, serviceType, domain
 	err = c.object.Call(c.interfaceForMember("ResolveService"), 0, iface, protocol, name, serviceType, domain, aprotocol, flags).
 		Store(&reply.Interface, &reply.Protocol, &reply.Name, &reply.Type, &reply.Domain,
 			&reply.Host, &reply.Aprotocol, &reply.Address, &reply.Port, &reply.Txt, &reply.Flags)
-	return reply, err
+	return reply, avahiError(err)
+}
+
+// ResolveHostNameContext is like ResolveHostName, but gives up when ctx is done.
+// The resolver is freed on the daemon before returning.
+func (c *Server) ResolveHostNameContext(ctx context.Context, iface, protocol int32, name string, aprotocol int32, flags uint32) (HostName, error) {
+	r, err := c.hostNameResolverNew(ctx, name, []Option{WithInterface(iface), WithProtocol(protocol), WithAddressProtocol(aprotocol), WithLookupFlags(flags)})
+	if err != nil {
+		return HostName{}, err
+	}
+
+	defer c.signalEmitterFree(r)
+
+	select {
+	case hostName := <-r.FoundChannel:
+		return hostName, nil
+	case err := <-r.FailureChannel:
+		return HostName{}, err
+	case <-ctx.Done():
+		return HostName{}, ctx.Err()
+	}
+}
+
+// ResolveAddressContext is like ResolveAddress, but gives up when ctx is done.
+// The resolver is freed on the daemon before returning.
+func (c *Server) ResolveAddressContext(ctx context.Context, iface, protocol int32, address string, flags uint32) (Address, error) {
+	r, err := c.addressResolverNew(ctx, address, []Option{WithInterface(iface), WithProtocol(protocol), WithLookupFlags(flags)})
+	if err != nil {
+		return Address{}, err
+	}
+
+	defer c.signalEmitterFree(r)
+
+	select {
+	case address := <-r.FoundChannel:
+		return address, nil
+	case err := <-r.FailureChannel:
+		return Address{}, err
+	case <-ctx.Done():
+		return Address{}, ctx.Err()
+	}
+}
+
+// ResolveServiceContext is like ResolveService, but gives up when ctx is done.
+// The resolver is freed on the daemon before returning.
+func (c *Server) ResolveServiceContext(ctx context.Context, iface, protocol int32, name, serviceType, domain string, aprotocol int32, flags uint32) (Service, error) {
+	r, err := c.serviceResolverNew(ctx, name, serviceType, []Option{WithInterface(iface), WithProtocol(protocol), WithDomain(domain), WithAddressProtocol(aprotocol), WithLookupFlags(flags)})
+	if err != nil {
+		return Service{}, err
+	}
+
+	defer c.signalEmitterFree(r)
+
+	select {
+	case service := <-r.FoundChannel:
+		return service, nil
+	case err := <-r.FailureChannel:
+		return Service{}, err
+	case <-ctx.Done():
+		return Service{}, ctx.Err()
+	}
 }
 
 // DomainBrowserNew ...
 func (c *Server) DomainBrowserNew(iface, protocol int32, domain string, btype int32, flags uint32) (*DomainBrowser, error) {
-	var o dbus.ObjectPath
+	return c.DomainBrowserNewWithOptions(btype, WithInterface(iface), WithProtocol(protocol), WithDomain(domain), WithLookupFlags(flags))
+}
 
-	err := c.object.Call(c.interfaceForMember("DomainBrowserNew"), 0, iface, protocol, domain, btype, flags).Store(&o)
+// DomainBrowserNewWithOptions creates a DomainBrowser for domains of type btype, e.g. DomainBrowserTypeBrowse.
+// It accepts WithInterface, WithInterfaceName, WithProtocol, WithDomain, WithLookupFlags and WithBuffer.
+func (c *Server) DomainBrowserNewWithOptions(btype int32, opts ...Option) (*DomainBrowser, error) {
+	o, err := c.options(opts)
 	if err != nil {
 		return nil, err
 	}
 
-	r, err := DomainBrowserNew(c.conn, o)
+	source := signalEmitterSource{objectType: "DomainBrowser", args: []interface{}{o.iface, o.protocol, o.domain, btype, o.flags}, startable: true,
+		queueSize: o.queueSize, queueOverflow: o.queueOverflow}
+
+	e, err := c.signalEmitterNew(context.Background(), source, func(path dbus.ObjectPath) (signalEmitter, error) {
+		return DomainBrowserNew(c.conn, path)
+	})
 	if err != nil {
 		return nil, err
 	}
 
-	return r, nil
+	return e.(*DomainBrowser), nil
 }
 
 // DomainBrowserFree ...
@@ -159,24 +426,28 @@ func (c *Server) DomainBrowserFree(r *DomainBrowser) {
 
 // ServiceTypeBrowserNew ...
 func (c *Server) ServiceTypeBrowserNew(iface, protocol int32, domain string, flags uint32) (*ServiceTypeBrowser, error) {
-	var o dbus.ObjectPath
-
-	c.mutex.Lock()
-	defer c.mutex.Unlock()
+	return c.ServiceTypeBrowserNewWithOptions(WithInterface(iface), WithProtocol(protocol), WithDomain(domain), WithLookupFlags(flags))
+}
 
-	err := c.object.Call(c.interfaceForMember("ServiceTypeBrowserNew"), 0, iface, protocol, domain, flags).Store(&o)
+// ServiceTypeBrowserNewWithOptions creates a ServiceTypeBrowser.
+// It accepts WithInterface, WithInterfaceName, WithProtocol, WithDomain, WithLookupFlags and WithBuffer.
+func (c *Server) ServiceTypeBrowserNewWithOptions(opts ...Option) (*ServiceTypeBrowser, error) {
+	o, err := c.options(opts)
 	if err != nil {
 		return nil, err
 	}
 
-	r, err := ServiceTypeBrowserNew(c.conn, o)
+	source := signalEmitterSource{objectType: "ServiceTypeBrowser", args: []interface{}{o.iface, o.protocol, o.domain, o.flags}, startable: true,
+		queueSize: o.queueSize, queueOverflow: o.queueOverflow}
+
+	e, err := c.signalEmitterNew(context.Background(), source, func(path dbus.ObjectPath) (signalEmitter, error) {
+		return ServiceTypeBrowserNew(c.conn, path)
+	})
 	if err != nil {
 		return nil, err
 	}
 
-	c.signalEmitters[o] = r
-
-	return r, nil
+	return e.(*ServiceTypeBrowser), nil
 }
 
 // ServiceTypeBrowserFree ...
@@ -186,24 +457,28 @@ func (c *Server) ServiceTypeBrowserFree(r *ServiceTypeBrowser) {
 
 // ServiceBrowserNew ...
 func (c *Server) ServiceBrowserNew(iface, protocol int32, serviceType string, domain string, flags uint32) (*ServiceBrowser, error) {
-	var o dbus.ObjectPath
-
-	c.mutex.Lock()
-	defer c.mutex.Unlock()
+	return c.ServiceBrowserNewWithOptions(serviceType, WithInterface(iface), WithProtocol(protocol), WithDomain(domain), WithLookupFlags(flags))
+}
 
-	err := c.object.Call(c.interfaceForMember("ServiceBrowserNew"), 0, iface, protocol, serviceType, domain, flags).Store(&o)
+// ServiceBrowserNewWithOptions creates a ServiceBrowser for services of serviceType.
+// It accepts WithInterface, WithInterfaceName, WithProtocol, WithDomain, WithLookupFlags and WithBuffer.
+func (c *Server) ServiceBrowserNewWithOptions(serviceType string, opts ...Option) (*ServiceBrowser, error) {
+	o, err := c.options(opts)
 	if err != nil {
 		return nil, err
 	}
 
-	r, err := ServiceBrowserNew(c.conn, o)
+	source := signalEmitterSource{objectType: "ServiceBrowser", args: []interface{}{o.iface, o.protocol, serviceType, o.domain, o.flags}, startable: true,
+		queueSize: o.queueSize, queueOverflow: o.queueOverflow}
+
+	e, err := c.signalEmitterNew(context.Background(), source, func(path dbus.ObjectPath) (signalEmitter, error) {
+		return ServiceBrowserNew(c.conn, path)
+	})
 	if err != nil {
 		return nil, err
 	}
 
-	c.signalEmitters[o] = r
-
-	return r, nil
+	return e.(*ServiceBrowser), nil
 }
 
 // ServiceBrowserFree ...
@@ -213,24 +488,33 @@ func (c *Server) ServiceBrowserFree(r *ServiceBrowser) {
 
 // ServiceResolverNew ...
 func (c *Server) ServiceResolverNew(iface, protocol int32, name, serviceType, domain string, aprotocol int32, flags uint32) (*ServiceResolver, error) {
-	var o dbus.ObjectPath
+	return c.ServiceResolverNewWithOptions(name, serviceType, WithInterface(iface), WithProtocol(protocol), WithDomain(domain),
+		WithAddressProtocol(aprotocol), WithLookupFlags(flags))
+}
 
-	c.mutex.Lock()
-	defer c.mutex.Unlock()
+// ServiceResolverNewWithOptions creates a ServiceResolver for the service name of serviceType. It accepts WithInterface,
+// WithInterfaceName, WithProtocol, WithDomain, WithAddressProtocol, WithLookupFlags and WithBuffer.
+func (c *Server) ServiceResolverNewWithOptions(name, serviceType string, opts ...Option) (*ServiceResolver, error) {
+	return c.serviceResolverNew(context.Background(), name, serviceType, opts)
+}
 
-	err := c.object.Call(c.interfaceForMember("ServiceResolverNew"), 0, iface, protocol, name, serviceType, domain, aprotocol, flags).Store(&o)
+func (c *Server) serviceResolverNew(ctx context.Context, name, serviceType string, opts []Option) (*ServiceResolver, error) {
+	o, err := c.options(opts)
 	if err != nil {
 		return nil, err
 	}
 
-	r, err := ServiceResolverNew(c.conn, o)
+	source := signalEmitterSource{objectType: "ServiceResolver", args: []interface{}{o.iface, o.protocol, name, serviceType, o.domain, o.aprotocol, o.flags}, startable: true,
+		queueSize: o.queueSize, queueOverflow: o.queueOverflow}
+
+	e, err := c.signalEmitterNew(ctx, source, func(path dbus.ObjectPath) (signalEmitter, error) {
+		return ServiceResolverNew(c.conn, path)
+	})
 	if err != nil {
 		return nil, err
 	}
 
-	c.signalEmitters[o] = r
-
-	return r, nil
+	return e.(*ServiceResolver), nil
 }
 
 // ServiceResolverFree ...
@@ -240,46 +524,67 @@ func (c *Server) ServiceResolverFree(r *ServiceResolver) {
 
 // HostNameResolverNew ...
 func (c *Server) HostNameResolverNew(iface, protocol int32, name string, aprotocol int32, flags uint32) (*HostNameResolver, error) {
-	var o dbus.ObjectPath
+	return c.HostNameResolverNewWithOptions(name, WithInterface(iface), WithProtocol(protocol), WithAddressProtocol(aprotocol), WithLookupFlags(flags))
+}
 
-	c.mutex.Lock()
-	defer c.mutex.Unlock()
+// HostNameResolverNewWithOptions creates a HostNameResolver for the host name. It accepts WithInterface,
+// WithInterfaceName, WithProtocol, WithAddressProtocol, WithLookupFlags and WithBuffer.
+func (c *Server) HostNameResolverNewWithOptions(name string, opts ...Option) (*HostNameResolver, error) {
+	return c.hostNameResolverNew(context.Background(), name, opts)
+}
 
-	err := c.object.Call(c.interfaceForMember("HostNameResolverNew"), 0, iface, protocol, name, aprotocol, flags).Store(&o)
+func (c *Server) hostNameResolverNew(ctx context.Context, name string, opts []Option) (*HostNameResolver, error) {
+	o, err := c.options(opts)
 	if err != nil {
 		return nil, err
 	}
 
-	r, err := HostNameResolverNew(c.conn, o)
+	source := signalEmitterSource{objectType: "HostNameResolver", args: []interface{}{o.iface, o.protocol, name, o.aprotocol, o.flags}, startable: true,
+		queueSize: o.queueSize, queueOverflow: o.queueOverflow}
+
+	e, err := c.signalEmitterNew(ctx, source, func(path dbus.ObjectPath) (signalEmitter, error) {
+		return HostNameResolverNew(c.conn, path)
+	})
 	if err != nil {
 		return nil, err
 	}
 
-	c.signalEmitters[o] = r
+	return e.(*HostNameResolver), nil
+}
 
-	return r, nil
+// HostNameResolverFree ...
+func (c *Server) HostNameResolverFree(r *HostNameResolver) {
+	c.signalEmitterFree(r)
 }
 
 // AddressResolverNew ...
 func (c *Server) AddressResolverNew(iface, protocol int32, address string, flags uint32) (*AddressResolver, error) {
-	var o dbus.ObjectPath
+	return c.AddressResolverNewWithOptions(address, WithInterface(iface), WithProtocol(protocol), WithLookupFlags(flags))
+}
 
-	c.mutex.Lock()
-	defer c.mutex.Unlock()
+// AddressResolverNewWithOptions creates an AddressResolver for the address. It accepts WithInterface,
+// WithInterfaceName, WithProtocol, WithLookupFlags and WithBuffer.
+func (c *Server) AddressResolverNewWithOptions(address string, opts ...Option) (*AddressResolver, error) {
+	return c.addressResolverNew(context.Background(), address, opts)
+}
 
-	err := c.object.Call(c.interfaceForMember("AddressResolverNew"), 0, iface, protocol, address, flags).Store(&o)
+func (c *Server) addressResolverNew(ctx context.Context, address string, opts []Option) (*AddressResolver, error) {
+	o, err := c.options(opts)
 	if err != nil {
 		return nil, err
 	}
 
-	r, err := AddressResolverNew(c.conn, o)
+	source := signalEmitterSource{objectType: "AddressResolver", args: []interface{}{o.iface, o.protocol, address, o.flags}, startable: true,
+		queueSize: o.queueSize, queueOverflow: o.queueOverflow}
+
+	e, err := c.signalEmitterNew(ctx, source, func(path dbus.ObjectPath) (signalEmitter, error) {
+		return AddressResolverNew(c.conn, path)
+	})
 	if err != nil {
 		return nil, err
 	}
 
-	c.signalEmitters[o] = r
-
-	return r, nil
+	return e.(*AddressResolver), nil
 }
 
 // AddressResolverFree ...
@@ -289,24 +594,28 @@ func (c *Server) AddressResolverFree(r *AddressResolver) {
 
 // RecordBrowserNew ...
 func (c *Server) RecordBrowserNew(iface, protocol int32, name string, class uint16, recordType uint16, flags uint32) (*RecordBrowser, error) {
-	var o dbus.ObjectPath
-
-	c.mutex.Lock()
-	defer c.mutex.Unlock()
+	return c.RecordBrowserNewWithOptions(name, class, recordType, WithInterface(iface), WithProtocol(protocol), WithLookupFlags(flags))
+}
 
-	err := c.object.Call(c.interfaceForMember("RecordBrowserNew"), 0, iface, protocol, name, class, recordType, flags).Store(&o)
+// RecordBrowserNewWithOptions creates a RecordBrowser for records of class and recordType named name.
+// It accepts WithInterface, WithInterfaceName, WithProtocol, WithLookupFlags and WithBuffer.
+func (c *Server) RecordBrowserNewWithOptions(name string, class, recordType uint16, opts ...Option) (*RecordBrowser, error) {
+	o, err := c.options(opts)
 	if err != nil {
 		return nil, err
 	}
 
-	r, err := RecordBrowserNew(c.conn, o)
+	source := signalEmitterSource{objectType: "RecordBrowser", args: []interface{}{o.iface, o.protocol, name, class, recordType, o.flags}, startable: true,
+		queueSize: o.queueSize, queueOverflow: o.queueOverflow}
+
+	e, err := c.signalEmitterNew(context.Background(), source, func(path dbus.ObjectPath) (signalEmitter, error) {
+		return RecordBrowserNew(c.conn, path)
+	})
 	if err != nil {
 		return nil, err
 	}
 
-	c.signalEmitters[o] = r
-
-	return r, nil
+	return e.(*RecordBrowser), nil
 }
 
 // RecordBrowserFree ...
@@ -320,7 +629,7 @@ func (c *Server) GetAPIVersion() (int32, error) {
 
 	err := c.object.Call(c.interfaceForMember("GetAPIVersion"), 0).Store(&i)
 	if err != nil {
-		return 0, err
+		return 0, avahiError(err)
 	}
 
 	return i, nil
@@ -332,7 +641,7 @@ func (c *Server) GetAlternativeHostName(name string) (string, error) {
 
 	err := c.object.Call(c.interfaceForMember("GetAlternativeHostName"), 0, name).Store(&s)
 	if err != nil {
-		return "", err
+		return "", avahiError(err)
 	}
 
 	return s, nil
@@ -344,7 +653,7 @@ func (c *Server) GetAlternativeServiceName(name string) (string, error) {
 
 	err := c.object.Call(c.interfaceForMember("GetAlternativeServiceName"), 0, name).Store(&s)
 	if err != nil {
-		return "", err
+		return "", avahiError(err)
 	}
 
 	return s, nil
@@ -356,7 +665,7 @@ func (c *Server) GetDomainName() (string, error) {
 
 	err := c.object.Call(c.interfaceForMember("GetDomainName"), 0).Store(&s)
 	if err != nil {
-		return "", err
+		return "", avahiError(err)
 	}
 
 	return s, nil
@@ -368,7 +677,7 @@ func (c *Server) GetHostName() (string, error) {
 
 	err := c.object.Call(c.interfaceForMember("GetHostName"), 0).Store(&s)
 	if err != nil {
-		return "", err
+		return "", avahiError(err)
 	}
 
 	return s, nil
@@ -380,7 +689,7 @@ func (c *Server) GetHostNameFqdn() (string, error) {
 
 	err := c.object.Call(c.interfaceForMember("GetHostNameFqdn"), 0).Store(&s)
 	if err != nil {
-		return "", err
+		return "", avahiError(err)
 	}
 
 	return s, nil
@@ -392,7 +701,7 @@ func (c *Server) GetLocalServiceCookie() (int32, error) {
 
 	err := c.object.Call(c.interfaceForMember("GetLocalServiceCookie"), 0).Store(&i)
 	if err != nil {
-		return 0, err
+		return 0, avahiError(err)
 	}
 
 	return i, nil
@@ -404,7 +713,7 @@ func (c *Server) GetNetworkInterfaceIndexByName(name string) (int32, error) {
 
 	err := c.object.Call(c.interfaceForMember("GetNetworkInterfaceIndexByName"), 0, name).Store(&i)
 	if err != nil {
-		return 0, err
+		return 0, avahiError(err)
 	}
 
 	return i, nil
@@ -416,7 +725,7 @@ func (c *Server) GetNetworkInterfaceNameByIndex(index int32) (string, error) {
 
 	err := c.object.Call(c.interfaceForMember("GetNetworkInterfaceNameByIndex"), 0, index).Store(&s)
 	if err != nil {
-		return "", err
+		return "", avahiError(err)
 	}
 
 	return s, nil
@@ -428,7 +737,7 @@ func (c *Server) GetState() (int32, error) {
 
 	err := c.object.Call(c.interfaceForMember("GetState"), 0).Store(&i)
 	if err != nil {
-		return 0, err
+		return 0, avahiError(err)
 	}
 
 	return i, nil
@@ -440,7 +749,7 @@ func (c *Server) GetVersionString() (string, error) {
 
 	err := c.object.Call(c.interfaceForMember("GetVersionString"), 0).Store(&s)
 	if err != nil {
-		return "", err
+		return "", avahiError(err)
 	}
 
 	return s, nil
@@ -452,7 +761,7 @@ func (c *Server) IsNSSSupportAvailable() (bool, error) {
 
 	err := c.object.Call(c.interfaceForMember("IsNSSSupportAvailable"), 0).Store(&b)
 	if err != nil {
-		return false, err
+		return false, avahiError(err)
 	}
 
 	return b, nil
@@ -460,5 +769,5 @@ func (c *Server) IsNSSSupportAvailable() (bool, error) {
 
 // SetServerName ...
 func (c *Server) SetServerName(name string) error {
-	return c.object.Call(c.interfaceForMember("SetServerName"), 0, name).Err
+	return avahiError(c.object.Call(c.interfaceForMember("SetServerName"), 0, name).Err)
 }
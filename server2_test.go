@@ -0,0 +1,75 @@
+package avahi_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/holoplot/go-avahi"
+	"github.com/holoplot/go-avahi/avahitest"
+)
+
+func TestServer2(t *testing.T) {
+	d, s := avahitest.NewServer(t)
+
+	printer := avahi.Service{
+		Interface: 2,
+		Protocol:  avahi.ProtoInet,
+		Name:      "Printer",
+		Type:      "_ipp._tcp",
+		Domain:    "local",
+	}
+	d.AddService(printer)
+
+	// With Prepare and Start, no signal is emitted before the browser is subscribed
+	for i := 0; i < 20; i++ {
+		b, err := s.ServiceBrowserNew(avahi.InterfaceUnspec, avahi.ProtoUnspec, "_ipp._tcp", "local", 0)
+		if err != nil {
+			t.Fatalf("ServiceBrowserNew() failed: %v", err)
+		}
+
+		select {
+		case <-b.AddChannel:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("browser %d missed a known service", i)
+		}
+
+		s.ServiceBrowserFree(b)
+	}
+
+	err := d.DisableServer2()
+	if err != nil {
+		t.Fatalf("DisableServer2() failed: %v", err)
+	}
+
+	for {
+		select {
+		case state := <-s.DaemonStateChannel:
+			if state.State != avahi.DaemonRestored {
+				continue
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("daemon not restored")
+		}
+		break
+	}
+
+	b, err := s.ServiceBrowserNew(avahi.InterfaceUnspec, avahi.ProtoUnspec, "_http._tcp", "local", 0)
+	if err != nil {
+		t.Fatalf("ServiceBrowserNew() without Server2 failed: %v", err)
+	}
+	defer s.ServiceBrowserFree(b)
+
+	web := printer
+	web.Name = "Web"
+	web.Type = "_http._tcp"
+	d.AddService(web)
+
+	select {
+	case service := <-b.AddChannel:
+		if service.Name != "Web" {
+			t.Fatalf("AddChannel delivered %+v", service)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no service found without Server2")
+	}
+}
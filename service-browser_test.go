@@ -0,0 +1,48 @@
+package avahi_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/holoplot/go-avahi"
+	"github.com/holoplot/go-avahi/avahitest"
+)
+
+func TestServiceBrowserAllForNow(t *testing.T) {
+	d, s := avahitest.NewServer(t)
+
+	d.AddService(avahi.Service{
+		Interface: 2,
+		Protocol:  avahi.ProtoInet,
+		Name:      "Printer",
+		Type:      "_ipp._tcp",
+		Domain:    "local",
+	})
+
+	b, err := s.ServiceBrowserNew(avahi.InterfaceUnspec, avahi.ProtoUnspec, "_ipp._tcp", "local", 0)
+	if err != nil {
+		t.Fatalf("ServiceBrowserNew() failed: %v", err)
+	}
+	defer s.ServiceBrowserFree(b)
+
+	select {
+	case service := <-b.AddChannel:
+		if service.Name != "Printer" {
+			t.Fatalf("AddChannel delivered %+v", service)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no service found")
+	}
+
+	select {
+	case <-b.CacheExhaustedChannel:
+	case <-time.After(5 * time.Second):
+		t.Fatal("no CacheExhausted")
+	}
+
+	select {
+	case <-b.AllForNowChannel:
+	case <-time.After(5 * time.Second):
+		t.Fatal("no AllForNow")
+	}
+}
@@ -0,0 +1,532 @@
+package avahi
+
+import (
+	"reflect"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	// ServiceDirectoryAdd - A service instance appeared and has been resolved
+	ServiceDirectoryAdd = 0
+	// ServiceDirectoryUpdate - The resolved data of a service instance changed, or it appeared on another interface or protocol
+	ServiceDirectoryUpdate = 1
+	// ServiceDirectoryRemove - A service instance disappeared from all interfaces and protocols
+	ServiceDirectoryRemove = 2
+)
+
+// A ServiceDirectoryEntry is a resolved service instance, merged over all
+// interfaces and protocols it was found on
+type ServiceDirectoryEntry struct {
+	Name   string
+	Type   string
+	Domain string
+	// Services holds one resolved Service per interface and protocol, ordered by interface and protocol
+	Services []Service
+}
+
+// A ServiceDirectoryEvent describes a change in a ServiceDirectory
+type ServiceDirectoryEvent struct {
+	Event int32
+	Entry ServiceDirectoryEntry
+}
+
+// Failed resolutions and browsers are retried after a delay that doubles from
+// serviceDirectoryRetryMin up to serviceDirectoryRetryMax
+const (
+	serviceDirectoryRetryMin = time.Second
+	serviceDirectoryRetryMax = time.Minute
+)
+
+type serviceDirectoryKey struct {
+	Name   string
+	Type   string
+	Domain string
+}
+
+// A ServiceDirectory keeps an always resolved view of all instances of a service type
+type ServiceDirectory struct {
+	// AllForNowChannel receives a value once the instances found by the initial cache
+	// dump are resolved, or failed to resolve. It does so again after browsing restarted.
+	AllForNowChannel chan struct{}
+	// FailureChannel receives an error whenever browsing failed. Browsing is retried.
+	FailureChannel chan error
+
+	server      *Server
+	watcher     *serverWatcher
+	iface       int32
+	protocol    int32
+	serviceType string
+	domain      string
+	flags       uint32
+
+	foundChannel  chan Service
+	failedChannel chan serviceKey
+	quitChannel   chan struct{}
+	quitOnce      sync.Once
+	doneChannel   chan struct{}
+	resolving     sync.WaitGroup
+
+	// browser, resolvers, pending, browsed and announced are only accessed by the run goroutine
+	browser   *ServiceBrowser
+	resolvers map[serviceKey]chan struct{}
+	// pending holds the instances of the initial cache dump that are not resolved yet
+	pending   map[serviceKey]bool
+	browsed   bool
+	announced bool
+
+	mutex       sync.Mutex
+	entries     map[serviceDirectoryKey]map[serviceKey]Service
+	subscribers map[chan ServiceDirectoryEvent]chan struct{}
+}
+
+// ServiceDirectoryNew creates a new ServiceDirectory for services of serviceType in domain.
+// Every instance found is resolved and kept resolved until it disappears. Instances that
+// fail to resolve are left out, or removed, until resolving them again succeeds. When
+// browsing fails, the error is sent to FailureChannel, all entries are removed and
+// browsing is retried.
+// When avahi-daemon restarts, all entries are removed and the service type is browsed again.
+func (c *Server) ServiceDirectoryNew(iface, protocol int32, serviceType, domain string, flags uint32) (*ServiceDirectory, error) {
+	b, err := c.ServiceBrowserNew(iface, protocol, serviceType, domain, flags)
+	if err != nil {
+		return nil, err
+	}
+
+	d := new(ServiceDirectory)
+	d.server = c
+	d.watcher = c.watch()
+	d.iface = iface
+	d.protocol = protocol
+	d.serviceType = serviceType
+	d.domain = domain
+	d.flags = flags
+	d.browser = b
+	d.AllForNowChannel = make(chan struct{}, 1)
+	d.FailureChannel = make(chan error, 1)
+	d.foundChannel = make(chan Service)
+	d.failedChannel = make(chan serviceKey)
+	d.quitChannel = make(chan struct{})
+	d.doneChannel = make(chan struct{})
+	d.resolvers = make(map[serviceKey]chan struct{})
+	d.pending = make(map[serviceKey]bool)
+	d.entries = make(map[serviceDirectoryKey]map[serviceKey]Service)
+	d.subscribers = make(map[chan ServiceDirectoryEvent]chan struct{})
+
+	go d.run()
+
+	return d, nil
+}
+
+// ServiceDirectoryFree stops a ServiceDirectory and frees its browser and resolvers.
+// All subscription channels are closed. Freeing a ServiceDirectory again has no effect.
+func (c *Server) ServiceDirectoryFree(d *ServiceDirectory) {
+	d.quitOnce.Do(func() {
+		close(d.quitChannel)
+	})
+
+	<-d.doneChannel
+}
+
+// Snapshot returns all currently known entries, ordered by name
+func (d *ServiceDirectory) Snapshot() []ServiceDirectoryEntry {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	entries := make([]ServiceDirectoryEntry, 0, len(d.entries))
+	for key := range d.entries {
+		entries = append(entries, d.entry(key))
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Name < entries[j].Name
+	})
+
+	return entries
+}
+
+// Subscribe returns a channel that receives an event for every change in the directory.
+// The channel initially receives a ServiceDirectoryAdd event for every entry already known.
+// Subscribers must keep reading from the channel until they call Unsubscribe.
+func (d *ServiceDirectory) Subscribe() chan ServiceDirectoryEvent {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	ch := make(chan ServiceDirectoryEvent, len(d.entries)+16)
+	d.subscribers[ch] = make(chan struct{})
+
+	for key := range d.entries {
+		ch <- ServiceDirectoryEvent{Event: ServiceDirectoryAdd, Entry: d.entry(key)}
+	}
+
+	return ch
+}
+
+// Unsubscribe stops delivering events to ch
+func (d *ServiceDirectory) Unsubscribe(ch chan ServiceDirectoryEvent) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	done, ok := d.subscribers[ch]
+	if ok {
+		close(done)
+		delete(d.subscribers, ch)
+	}
+}
+
+// entry builds the entry for key. The caller must hold d.mutex.
+func (d *ServiceDirectory) entry(key serviceDirectoryKey) ServiceDirectoryEntry {
+	e := ServiceDirectoryEntry{Name: key.Name, Type: key.Type, Domain: key.Domain}
+
+	for _, s := range d.entries[key] {
+		e.Services = append(e.Services, s)
+	}
+
+	sort.Slice(e.Services, func(i, j int) bool {
+		if e.Services[i].Interface != e.Services[j].Interface {
+			return e.Services[i].Interface < e.Services[j].Interface
+		}
+		return e.Services[i].Protocol < e.Services[j].Protocol
+	})
+
+	return e
+}
+
+func (d *ServiceDirectory) run() {
+	defer close(d.doneChannel)
+
+	var retry <-chan time.Time
+	retryDelay := serviceDirectoryRetryMin
+
+	for {
+		var addChannel, removeChannel chan Service
+		var allForNowChannel chan struct{}
+		var failureChannel chan error
+		if d.browser != nil {
+			addChannel = d.browser.AddChannel
+			removeChannel = d.browser.RemoveChannel
+			allForNowChannel = d.browser.AllForNowChannel
+			failureChannel = d.browser.FailureChannel
+		}
+
+		select {
+		case s := <-addChannel:
+			key := serviceKeyOf(s)
+			if _, ok := d.resolvers[key]; ok {
+				continue
+			}
+
+			stop := make(chan struct{})
+			d.resolvers[key] = stop
+
+			if !d.browsed {
+				d.pending[key] = true
+			}
+
+			d.resolving.Add(1)
+			go d.resolve(s, stop)
+
+		case s := <-removeChannel:
+			key := serviceKeyOf(s)
+			if stop, ok := d.resolvers[key]; ok {
+				close(stop)
+				delete(d.resolvers, key)
+			}
+
+			d.remove(key)
+			d.resolved(key)
+
+		case <-allForNowChannel:
+			d.browsed = true
+			d.announce()
+
+		case err := <-failureChannel:
+			// The next browser reports no removals of what vanished in between, start from scratch
+			d.stopResolvers()
+			d.clear()
+
+			d.server.ServiceBrowserFree(d.browser)
+			d.browser = nil
+
+			d.fail(err)
+
+			retry = time.After(retryDelay)
+
+		case s := <-d.foundChannel:
+			if _, ok := d.resolvers[serviceKeyOf(s)]; ok {
+				d.update(s)
+				d.resolved(serviceKeyOf(s))
+			}
+
+		case key := <-d.failedChannel:
+			if _, ok := d.resolvers[key]; ok {
+				d.remove(key)
+				d.resolved(key)
+			}
+
+		case state := <-d.watcher.daemonChannel:
+			if state.State != DaemonRestored {
+				continue
+			}
+
+			// Entries of the previous daemon may be stale, browse again from scratch
+			d.stopResolvers()
+			d.clear()
+
+			if d.browser != nil {
+				d.server.ServiceBrowserFree(d.browser)
+				d.browser = nil
+			}
+
+			retry = nil
+			retryDelay = serviceDirectoryRetryMin
+
+			if !d.browse() {
+				retry = time.After(retryDelay)
+			}
+
+		case <-retry:
+			retry = nil
+
+			if d.browse() {
+				retryDelay = serviceDirectoryRetryMin
+			} else {
+				retryDelay = nextRetryDelay(retryDelay)
+				retry = time.After(retryDelay)
+			}
+
+		case <-d.quitChannel:
+			d.stopResolvers()
+			d.server.unwatch(d.watcher)
+
+			if d.browser != nil {
+				d.server.ServiceBrowserFree(d.browser)
+			}
+
+			d.resolving.Wait()
+
+			d.mutex.Lock()
+			for ch, done := range d.subscribers {
+				close(done)
+				close(ch)
+				delete(d.subscribers, ch)
+			}
+			d.mutex.Unlock()
+
+			return
+		}
+	}
+}
+
+// browse creates the browser and reports whether that succeeded
+func (d *ServiceDirectory) browse() bool {
+	b, err := d.server.ServiceBrowserNew(d.iface, d.protocol, d.serviceType, d.domain, d.flags)
+	if err != nil {
+		d.fail(err)
+		return false
+	}
+
+	d.browser = b
+	d.browsed = false
+	d.announced = false
+	d.pending = make(map[serviceKey]bool)
+
+	return true
+}
+
+// resolved marks key as no longer pending
+func (d *ServiceDirectory) resolved(key serviceKey) {
+	delete(d.pending, key)
+	d.announce()
+}
+
+// announce sends on AllForNowChannel once all instances of the initial cache dump are resolved
+func (d *ServiceDirectory) announce() {
+	if !d.browsed || d.announced || len(d.pending) > 0 {
+		return
+	}
+
+	d.announced = true
+
+	select {
+	case d.AllForNowChannel <- struct{}{}:
+	default:
+	}
+}
+
+// fail sends err to FailureChannel, unless an earlier error was not received yet
+func (d *ServiceDirectory) fail(err error) {
+	select {
+	case d.FailureChannel <- err:
+	default:
+	}
+}
+
+func (d *ServiceDirectory) stopResolvers() {
+	for key, stop := range d.resolvers {
+		close(stop)
+		delete(d.resolvers, key)
+	}
+}
+
+func nextRetryDelay(delay time.Duration) time.Duration {
+	delay *= 2
+	if delay > serviceDirectoryRetryMax {
+		delay = serviceDirectoryRetryMax
+	}
+
+	return delay
+}
+
+// resolve keeps s resolved until stop is closed and forwards everything it finds to the
+// run goroutine. When resolving fails, the failure is reported and resolving is retried.
+func (d *ServiceDirectory) resolve(s Service, stop chan struct{}) {
+	defer d.resolving.Done()
+
+	delay := serviceDirectoryRetryMin
+
+	for {
+		found, stopped := d.resolveOnce(s, stop)
+		if stopped {
+			return
+		}
+
+		select {
+		case d.failedChannel <- serviceKeyOf(s):
+		case <-stop:
+			return
+		}
+
+		if found {
+			delay = serviceDirectoryRetryMin
+		}
+
+		select {
+		case <-time.After(delay):
+		case <-stop:
+			return
+		}
+
+		delay = nextRetryDelay(delay)
+	}
+}
+
+// resolveOnce runs a ServiceResolver for s until it fails or stop is closed.
+// It reports whether s was found and whether stop was closed.
+func (d *ServiceDirectory) resolveOnce(s Service, stop chan struct{}) (bool, bool) {
+	r, err := d.server.ServiceResolverNew(s.Interface, s.Protocol, s.Name, s.Type, s.Domain, ProtoUnspec, 0)
+	if err != nil {
+		return false, false
+	}
+
+	defer d.server.ServiceResolverFree(r)
+
+	found := false
+
+	for {
+		select {
+		case service := <-r.FoundChannel:
+			found = true
+
+			select {
+			case d.foundChannel <- service:
+			case <-stop:
+				return found, true
+			}
+		case <-r.FailureChannel:
+			return found, false
+		case <-stop:
+			return found, true
+		}
+	}
+}
+
+func (d *ServiceDirectory) update(s Service) {
+	key := serviceDirectoryKey{s.Name, s.Type, s.Domain}
+	event := int32(ServiceDirectoryUpdate)
+
+	d.mutex.Lock()
+
+	services, ok := d.entries[key]
+	if !ok {
+		services = make(map[serviceKey]Service)
+		d.entries[key] = services
+		event = ServiceDirectoryAdd
+	}
+
+	if old, ok := services[serviceKeyOf(s)]; ok && reflect.DeepEqual(old, s) {
+		d.mutex.Unlock()
+		return
+	}
+
+	services[serviceKeyOf(s)] = s
+
+	d.publish(ServiceDirectoryEvent{Event: event, Entry: d.entry(key)})
+}
+
+func (d *ServiceDirectory) remove(k serviceKey) {
+	key := serviceDirectoryKey{k.Name, k.Type, k.Domain}
+
+	d.mutex.Lock()
+
+	services, ok := d.entries[key]
+	if !ok {
+		d.mutex.Unlock()
+		return
+	}
+
+	if _, ok := services[k]; !ok {
+		d.mutex.Unlock()
+		return
+	}
+
+	delete(services, k)
+
+	if len(services) > 0 {
+		d.publish(ServiceDirectoryEvent{Event: ServiceDirectoryUpdate, Entry: d.entry(key)})
+		return
+	}
+
+	event := ServiceDirectoryEvent{Event: ServiceDirectoryRemove, Entry: d.entry(key)}
+	delete(d.entries, key)
+
+	d.publish(event)
+}
+
+// clear removes all entries
+func (d *ServiceDirectory) clear() {
+	for {
+		d.mutex.Lock()
+
+		if len(d.entries) == 0 {
+			d.mutex.Unlock()
+			return
+		}
+
+		for key := range d.entries {
+			event := ServiceDirectoryEvent{Event: ServiceDirectoryRemove, Entry: d.entry(key)}
+			delete(d.entries, key)
+
+			d.publish(event)
+			break
+		}
+	}
+}
+
+// publish sends event to all subscribers. The caller must hold d.mutex,
+// which is released before blocking on any subscriber.
+func (d *ServiceDirectory) publish(event ServiceDirectoryEvent) {
+	subscribers := make(map[chan ServiceDirectoryEvent]chan struct{}, len(d.subscribers))
+	for ch, done := range d.subscribers {
+		subscribers[ch] = done
+	}
+
+	d.mutex.Unlock()
+
+	for ch, done := range subscribers {
+		select {
+		case ch <- event:
+		case <-done:
+		}
+	}
+}
@@ -0,0 +1,234 @@
+package avahi_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/holoplot/go-avahi"
+	"github.com/holoplot/go-avahi/avahitest"
+)
+
+var directoryService = avahi.Service{
+	Interface: 2,
+	Protocol:  avahi.ProtoInet,
+	Name:      "Printer",
+	Type:      "_ipp._tcp",
+	Domain:    "local",
+	Host:      "printer.local",
+	Aprotocol: avahi.ProtoInet,
+	Address:   "192.168.1.20",
+	Port:      631,
+	Txt:       [][]byte{[]byte("txtvers=1")},
+	Flags:     avahi.LookupResultMulticast,
+}
+
+func expectDirectoryEvent(t *testing.T, events chan avahi.ServiceDirectoryEvent, event int32, services int) avahi.ServiceDirectoryEntry {
+	t.Helper()
+
+	select {
+	case e := <-events:
+		if e.Event != event || len(e.Entry.Services) != services {
+			t.Fatalf("received %+v, expected event %d with %d services", e, event, services)
+		}
+		return e.Entry
+	case <-time.After(5 * time.Second):
+		t.Fatalf("no event %d", event)
+	}
+
+	return avahi.ServiceDirectoryEntry{}
+}
+
+func TestServiceDirectory(t *testing.T) {
+	d, s := avahitest.NewServer(t)
+
+	dir, err := s.ServiceDirectoryNew(avahi.InterfaceUnspec, avahi.ProtoUnspec, "_ipp._tcp", "local", 0)
+	if err != nil {
+		t.Fatalf("ServiceDirectoryNew() failed: %v", err)
+	}
+	defer s.ServiceDirectoryFree(dir)
+
+	events := dir.Subscribe()
+
+	d.AddService(directoryService)
+	entry := expectDirectoryEvent(t, events, avahi.ServiceDirectoryAdd, 1)
+	if entry.Name != "Printer" || entry.Services[0].Port != 631 {
+		t.Fatalf("added %+v", entry)
+	}
+
+	// The same instance on another interface is merged into the entry
+	other := directoryService
+	other.Interface = 1
+	d.AddService(other)
+	entry = expectDirectoryEvent(t, events, avahi.ServiceDirectoryUpdate, 2)
+	if entry.Services[0].Interface != 1 || entry.Services[1].Interface != 2 {
+		t.Fatalf("merged entry is %+v", entry)
+	}
+
+	updated := directoryService
+	updated.Txt = [][]byte{[]byte("txtvers=2")}
+	d.AddService(updated)
+	entry = expectDirectoryEvent(t, events, avahi.ServiceDirectoryUpdate, 2)
+	if string(entry.Services[1].Txt[0]) != "txtvers=2" {
+		t.Fatalf("updated entry is %+v", entry)
+	}
+
+	if entries := dir.Snapshot(); len(entries) != 1 || entries[0].Name != "Printer" {
+		t.Fatalf("Snapshot() returned %+v", entries)
+	}
+
+	// New subscribers start with the known entries
+	late := dir.Subscribe()
+	expectDirectoryEvent(t, late, avahi.ServiceDirectoryAdd, 2)
+	dir.Unsubscribe(late)
+
+	d.RemoveService(other)
+	expectDirectoryEvent(t, events, avahi.ServiceDirectoryUpdate, 1)
+
+	d.RemoveService(directoryService)
+	expectDirectoryEvent(t, events, avahi.ServiceDirectoryRemove, 0)
+
+	if entries := dir.Snapshot(); len(entries) != 0 {
+		t.Fatalf("Snapshot() after removal returned %+v", entries)
+	}
+}
+
+func TestServiceDirectoryResolveFailure(t *testing.T) {
+	d, s := avahitest.NewServer(t)
+
+	dir, err := s.ServiceDirectoryNew(avahi.InterfaceUnspec, avahi.ProtoUnspec, "_ipp._tcp", "local", 0)
+	if err != nil {
+		t.Fatalf("ServiceDirectoryNew() failed: %v", err)
+	}
+	defer s.ServiceDirectoryFree(dir)
+
+	events := dir.Subscribe()
+
+	d.AddService(directoryService)
+	expectDirectoryEvent(t, events, avahi.ServiceDirectoryAdd, 1)
+
+	d.SetResolvable(directoryService, false)
+	expectDirectoryEvent(t, events, avahi.ServiceDirectoryRemove, 0)
+
+	// Resolving is retried while the instance is still browsed
+	d.SetResolvable(directoryService, true)
+	expectDirectoryEvent(t, events, avahi.ServiceDirectoryAdd, 1)
+}
+
+func TestServiceDirectoryRestart(t *testing.T) {
+	d, s := avahitest.NewServer(t)
+
+	dir, err := s.ServiceDirectoryNew(avahi.InterfaceUnspec, avahi.ProtoUnspec, "_ipp._tcp", "local", 0)
+	if err != nil {
+		t.Fatalf("ServiceDirectoryNew() failed: %v", err)
+	}
+	defer s.ServiceDirectoryFree(dir)
+
+	events := dir.Subscribe()
+
+	d.AddService(directoryService)
+	expectDirectoryEvent(t, events, avahi.ServiceDirectoryAdd, 1)
+
+	err = d.Restart()
+	if err != nil {
+		t.Fatalf("Restart() failed: %v", err)
+	}
+
+	expectDirectoryEvent(t, events, avahi.ServiceDirectoryRemove, 1)
+	expectDirectoryEvent(t, events, avahi.ServiceDirectoryAdd, 1)
+}
+
+func TestServiceDirectoryAllForNow(t *testing.T) {
+	d, s := avahitest.NewServer(t)
+
+	unresolvable := directoryService
+	unresolvable.Name = "Offline"
+
+	d.AddService(directoryService)
+	d.AddService(unresolvable)
+	d.SetResolvable(unresolvable, false)
+
+	dir, err := s.ServiceDirectoryNew(avahi.InterfaceUnspec, avahi.ProtoUnspec, "_ipp._tcp", "local", 0)
+	if err != nil {
+		t.Fatalf("ServiceDirectoryNew() failed: %v", err)
+	}
+	defer s.ServiceDirectoryFree(dir)
+
+	select {
+	case <-dir.AllForNowChannel:
+	case <-time.After(5 * time.Second):
+		t.Fatal("no AllForNow")
+	}
+
+	if entries := dir.Snapshot(); len(entries) != 1 || entries[0].Name != "Printer" {
+		t.Fatalf("Snapshot() at AllForNow returned %+v", entries)
+	}
+}
+
+func TestServiceDirectoryBrowserFailure(t *testing.T) {
+	d, s := avahitest.NewServer(t)
+
+	dir, err := s.ServiceDirectoryNew(avahi.InterfaceUnspec, avahi.ProtoUnspec, "_ipp._tcp", "local", 0)
+	if err != nil {
+		t.Fatalf("ServiceDirectoryNew() failed: %v", err)
+	}
+	defer s.ServiceDirectoryFree(dir)
+
+	events := dir.Subscribe()
+
+	d.AddService(directoryService)
+	expectDirectoryEvent(t, events, avahi.ServiceDirectoryAdd, 1)
+
+	d.FailBrowsers(avahi.ErrNoMemory)
+	expectDirectoryEvent(t, events, avahi.ServiceDirectoryRemove, 1)
+
+	select {
+	case err := <-dir.FailureChannel:
+		if !errors.Is(err, avahi.ErrNoMemory) {
+			t.Fatalf("FailureChannel delivered %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no browser failure")
+	}
+
+	// Browsing is retried after the failure, without the service that vanished meanwhile
+	d.RemoveService(directoryService)
+
+	scanner := directoryService
+	scanner.Name = "Scanner"
+	d.AddService(scanner)
+
+	if entry := expectDirectoryEvent(t, events, avahi.ServiceDirectoryAdd, 1); entry.Name != "Scanner" {
+		t.Fatalf("added %+v after the failure", entry)
+	}
+
+	if entries := dir.Snapshot(); len(entries) != 1 {
+		t.Fatalf("Snapshot() returned %+v", entries)
+	}
+}
+
+func TestServiceDirectoryFree(t *testing.T) {
+	d, s := avahitest.NewServer(t)
+
+	d.AddService(directoryService)
+
+	dir, err := s.ServiceDirectoryNew(avahi.InterfaceUnspec, avahi.ProtoUnspec, "_ipp._tcp", "local", 0)
+	if err != nil {
+		t.Fatalf("ServiceDirectoryNew() failed: %v", err)
+	}
+
+	events := dir.Subscribe()
+	expectDirectoryEvent(t, events, avahi.ServiceDirectoryAdd, 1)
+
+	s.ServiceDirectoryFree(dir)
+	s.ServiceDirectoryFree(dir)
+
+	select {
+	case _, ok := <-events:
+		if ok {
+			t.Fatal("subscription channel delivered an event after ServiceDirectoryFree()")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("subscription channel not closed")
+	}
+}
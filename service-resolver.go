@@ -10,7 +10,12 @@ import (
 type ServiceResolver struct {
 	object       dbus.BusObject
 	FoundChannel chan Service
-	closeCh      chan struct{}
+
+	// FailureChannel receives the error reported by the daemon when resolving failed.
+	FailureChannel chan error
+
+	closeCh chan struct{}
+	queue   *signalQueue
 }
 
 // ServiceResolverNew returns a new mDNS service resolver
@@ -20,6 +25,8 @@ func ServiceResolverNew(conn *dbus.Conn, path dbus.ObjectPath) (*ServiceResolver
 	c.object = conn.Object("org.freedesktop.Avahi", path)
 	c.FoundChannel = make(chan Service)
 	c.closeCh = make(chan struct{})
+	c.queue = newSignalQueue()
+	c.FailureChannel = make(chan error, 1)
 
 	return c, nil
 }
@@ -35,11 +42,39 @@ func (c *ServiceResolver) free() {
 	c.object.Call(c.interfaceForMember("Free"), 0)
 }
 
+// Dropped returns the number of signals discarded because the queue was full
+func (c *ServiceResolver) Dropped() uint64 {
+	return c.queue.droppedCount()
+}
+
+func (c *ServiceResolver) getSignalQueue() *signalQueue {
+	return c.queue
+}
+
 func (c *ServiceResolver) getObjectPath() dbus.ObjectPath {
 	return c.object.Path()
 }
 
+func (c *ServiceResolver) restore(conn *dbus.Conn, path dbus.ObjectPath) error {
+	c.object = conn.Object("org.freedesktop.Avahi", path)
+	return nil
+}
+
 func (c *ServiceResolver) dispatchSignal(signal *dbus.Signal) error {
+	if signal.Name == c.interfaceForMember("Failure") {
+		var e string
+		err := dbus.Store(signal.Body, &e)
+		if err != nil {
+			return err
+		}
+
+		select {
+		case c.FailureChannel <- avahiErrorFromString(e):
+		default:
+		}
+		return nil
+	}
+
 	if signal.Name == c.interfaceForMember("Found") {
 		var service Service
 		err := dbus.Store(signal.Body, &service.Interface, &service.Protocol,
@@ -0,0 +1,30 @@
+package avahi_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/holoplot/go-avahi"
+	"github.com/holoplot/go-avahi/avahitest"
+)
+
+func TestServiceResolverFailure(t *testing.T) {
+	_, s := avahitest.NewServer(t)
+
+	r, err := s.ServiceResolverNew(avahi.InterfaceUnspec, avahi.ProtoUnspec, "Missing", "_ipp._tcp", "local", avahi.ProtoUnspec, 0)
+	if err != nil {
+		t.Fatalf("ServiceResolverNew() failed: %v", err)
+	}
+	defer s.ServiceResolverFree(r)
+
+	select {
+	case service := <-r.FoundChannel:
+		t.Fatalf("resolved missing service as %+v", service)
+	case err := <-r.FailureChannel:
+		if err == nil {
+			t.Fatal("FailureChannel delivered no error")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no Failure")
+	}
+}
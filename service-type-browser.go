@@ -11,7 +11,17 @@ type ServiceTypeBrowser struct {
 	object        dbus.BusObject
 	AddChannel    chan ServiceType
 	RemoveChannel chan ServiceType
-	closeCh       chan struct{}
+
+	// AllForNowChannel receives a value once the initial cache dump is complete
+	// and no further items are expected in the near future.
+	AllForNowChannel chan struct{}
+	// CacheExhaustedChannel receives a value once all entries from the daemon's cache have been delivered.
+	CacheExhaustedChannel chan struct{}
+	// FailureChannel receives the error reported by the daemon when browsing failed.
+	FailureChannel chan error
+
+	closeCh chan struct{}
+	queue   *signalQueue
 }
 
 // ServiceTypeBrowserNew creates a new browser for mDNS service types
@@ -22,6 +32,10 @@ func ServiceTypeBrowserNew(conn *dbus.Conn, path dbus.ObjectPath) (*ServiceTypeB
 	c.AddChannel = make(chan ServiceType)
 	c.RemoveChannel = make(chan ServiceType)
 	c.closeCh = make(chan struct{})
+	c.queue = newSignalQueue()
+	c.AllForNowChannel = make(chan struct{}, 1)
+	c.CacheExhaustedChannel = make(chan struct{}, 1)
+	c.FailureChannel = make(chan error, 1)
 
 	return c, nil
 }
@@ -37,11 +51,54 @@ func (c *ServiceTypeBrowser) free() {
 	c.object.Call(c.interfaceForMember("Free"), 0)
 }
 
+// Dropped returns the number of signals discarded because the queue was full
+func (c *ServiceTypeBrowser) Dropped() uint64 {
+	return c.queue.droppedCount()
+}
+
+func (c *ServiceTypeBrowser) getSignalQueue() *signalQueue {
+	return c.queue
+}
+
 func (c *ServiceTypeBrowser) getObjectPath() dbus.ObjectPath {
 	return c.object.Path()
 }
 
+func (c *ServiceTypeBrowser) restore(conn *dbus.Conn, path dbus.ObjectPath) error {
+	c.object = conn.Object("org.freedesktop.Avahi", path)
+	return nil
+}
+
 func (c *ServiceTypeBrowser) dispatchSignal(signal *dbus.Signal) error {
+	switch signal.Name {
+	case c.interfaceForMember("AllForNow"):
+		select {
+		case c.AllForNowChannel <- struct{}{}:
+		default:
+		}
+		return nil
+
+	case c.interfaceForMember("CacheExhausted"):
+		select {
+		case c.CacheExhaustedChannel <- struct{}{}:
+		default:
+		}
+		return nil
+
+	case c.interfaceForMember("Failure"):
+		var e string
+		err := dbus.Store(signal.Body, &e)
+		if err != nil {
+			return err
+		}
+
+		select {
+		case c.FailureChannel <- avahiErrorFromString(e):
+		default:
+		}
+		return nil
+	}
+
 	if signal.Name == c.interfaceForMember("ItemNew") || signal.Name == c.interfaceForMember("ItemRemove") {
 		var serviceType ServiceType
 		err := dbus.Store(signal.Body, &serviceType.Interface, &serviceType.Protocol, &serviceType.Type, &serviceType.Domain, &serviceType.Flags)
@@ -1,23 +1,192 @@
 package avahi
 
-import dbus "github.com/godbus/dbus/v5"
+import (
+	"context"
+	"fmt"
+
+	dbus "github.com/godbus/dbus/v5"
+)
 
 type signalEmitter interface {
 	dispatchSignal(signal *dbus.Signal) error
 	getObjectPath() dbus.ObjectPath
+	getSignalQueue() *signalQueue
+	restore(conn *dbus.Conn, path dbus.ObjectPath) error
 	free()
 }
 
-func (c *Server) signalEmitterFree(e signalEmitter) {
+// signalEmitterSource records the Server method call that created a signal
+// emitter, so the object can be created again after avahi-daemon restarted.
+// Startable objects are created with the Server2 Prepare method and only
+// begin emitting signals once Start is called, if the daemon supports it.
+type signalEmitterSource struct {
+	objectType string
+	args       []interface{}
+	startable  bool
+
+	// queueSize and queueOverflow override the Server's queue policy if queueSize is set
+	queueSize     int
+	queueOverflow int32
+}
+
+// signalEmitterNew creates the object described by source, wraps it with
+// newEmitter and registers it.
+func (c *Server) signalEmitterNew(ctx context.Context, source signalEmitterSource, newEmitter func(path dbus.ObjectPath) (signalEmitter, error)) (signalEmitter, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	o, err := c.signalEmitterCreate(ctx, source)
+	if err != nil {
+		return nil, err
+	}
+
+	e, err := newEmitter(o)
+	if err != nil {
+		return nil, err
+	}
+
+	err = c.signalEmitterAdd(ctx, e, source)
+	if err != nil {
+		return nil, err
+	}
+
+	return e, nil
+}
+
+// signalEmitterCreate creates the object described by source and returns
+// its path. The caller must hold c.mutex.
+func (c *Server) signalEmitterCreate(ctx context.Context, source signalEmitterSource) (dbus.ObjectPath, error) {
+	var o dbus.ObjectPath
+	var err error
+
+	if source.startable && c.hasServer2() {
+		err = c.object.CallWithContext(ctx, "org.freedesktop.Avahi.Server2."+source.objectType+"Prepare", 0, source.args...).Store(&o)
+	} else {
+		err = c.object.CallWithContext(ctx, c.interfaceForMember(source.objectType+"New"), 0, source.args...).Store(&o)
+	}
+
+	if err != nil {
+		return "", avahiError(err)
+	}
+
+	return o, nil
+}
+
+// signalEmitterStart starts a prepared object, after it has been registered
+// for signal dispatching. The caller must hold c.mutex.
+func (c *Server) signalEmitterStart(ctx context.Context, e signalEmitter, source signalEmitterSource) error {
+	if !source.startable || !c.server2 {
+		return nil
+	}
+
+	o := c.conn.Object("org.freedesktop.Avahi", e.getObjectPath())
+
+	return avahiError(o.CallWithContext(ctx, "org.freedesktop.Avahi."+source.objectType+".Start", 0).Err)
+}
+
+// signalEmitterAdd registers e for signal dispatching and starts it. The
+// caller must hold c.mutex.
+func (c *Server) signalEmitterAdd(ctx context.Context, e signalEmitter, source signalEmitterSource) error {
 	o := e.getObjectPath()
+	q := e.getSignalQueue()
+
+	if source.queueSize > 0 {
+		q.setPolicy(source.queueSize, source.queueOverflow)
+	} else {
+		q.setPolicy(c.queueSize, c.queueOverflow)
+	}
+	go q.run(e.dispatchSignal)
+
+	c.signalEmitters[o] = e
+	c.signalEmitterSources[e] = source
 
+	err := c.signalEmitterMatch(ctx, o)
+	if err == nil {
+		err = c.signalEmitterStart(ctx, e, source)
+	}
+
+	if err != nil {
+		delete(c.signalEmitters, o)
+		delete(c.signalEmitterSources, e)
+		c.signalEmitterUnmatch(o)
+		q.close()
+		e.free()
+
+		return err
+	}
+
+	return nil
+}
+
+func (c *Server) signalEmitterFree(e signalEmitter) {
 	c.mutex.Lock()
 	defer c.mutex.Unlock()
 
-	_, ok := c.signalEmitters[o]
-	if ok {
+	// Close() frees all emitters, so e may be gone already
+	if _, ok := c.signalEmitterSources[e]; !ok {
+		return
+	}
+
+	o := e.getObjectPath()
+
+	if c.signalEmitters[o] == e {
 		delete(c.signalEmitters, o)
 	}
+	delete(c.signalEmitterSources, e)
 
+	c.signalEmitterUnmatch(o)
+	e.getSignalQueue().close()
 	e.free()
 }
+
+func signalEmitterMatchRule(path dbus.ObjectPath) string {
+	return fmt.Sprintf("type='signal',sender='org.freedesktop.Avahi',path='%s'", path)
+}
+
+// signalEmitterMatch subscribes to the signals of the object at path, so
+// only signals of our own objects are routed to this connection.
+func (c *Server) signalEmitterMatch(ctx context.Context, path dbus.ObjectPath) error {
+	return c.conn.BusObject().CallWithContext(ctx, "org.freedesktop.DBus.AddMatch", 0, signalEmitterMatchRule(path)).Err
+}
+
+func (c *Server) signalEmitterUnmatch(path dbus.ObjectPath) {
+	c.conn.BusObject().Call("org.freedesktop.DBus.RemoveMatch", 0, signalEmitterMatchRule(path))
+}
+
+// signalEmittersRestore re-creates all registered signal emitters on a newly
+// started avahi-daemon. The caller must hold c.mutex.
+func (c *Server) signalEmittersRestore() error {
+	var firstErr error
+
+	for o := range c.signalEmitters {
+		c.signalEmitterUnmatch(o)
+	}
+
+	c.signalEmitters = make(map[dbus.ObjectPath]signalEmitter)
+
+	for e, source := range c.signalEmitterSources {
+		o, err := c.signalEmitterCreate(context.Background(), source)
+		if err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+
+		c.signalEmitters[o] = e
+
+		err = e.restore(c.conn, o)
+		if err == nil {
+			err = c.signalEmitterMatch(context.Background(), o)
+		}
+		if err == nil {
+			err = c.signalEmitterStart(context.Background(), e, source)
+		}
+
+		if err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	return firstErr
+}
@@ -0,0 +1,74 @@
+package avahi_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/holoplot/go-avahi"
+	"github.com/holoplot/go-avahi/avahitest"
+)
+
+func TestSignalRouting(t *testing.T) {
+	d, err := avahitest.New()
+	if errors.Is(err, avahitest.ErrNoBusDaemon) {
+		t.Skip(err)
+	}
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	defer d.Close()
+
+	conn, err := d.Conn()
+	if err != nil {
+		t.Fatalf("Conn() failed: %v", err)
+	}
+	defer conn.Close()
+
+	// Both servers receive all signals matched on the shared connection
+	s, err := avahi.ServerNew(conn)
+	if err != nil {
+		t.Fatalf("ServerNew() failed: %v", err)
+	}
+	defer s.Close()
+
+	other, err := avahi.ServerNew(conn)
+	if err != nil {
+		t.Fatalf("ServerNew() failed: %v", err)
+	}
+	defer other.Close()
+
+	b, err := s.ServiceBrowserNew(avahi.InterfaceUnspec, avahi.ProtoUnspec, "_ipp._tcp", "local", 0)
+	if err != nil {
+		t.Fatalf("ServiceBrowserNew() failed: %v", err)
+	}
+
+	ob, err := other.ServiceBrowserNew(avahi.InterfaceUnspec, avahi.ProtoUnspec, "_http._tcp", "local", 0)
+	if err != nil {
+		t.Fatalf("ServiceBrowserNew() failed: %v", err)
+	}
+
+	d.AddService(avahi.Service{
+		Interface: 2,
+		Protocol:  avahi.ProtoInet,
+		Name:      "Printer",
+		Type:      "_ipp._tcp",
+		Domain:    "local",
+	})
+
+	select {
+	case service := <-b.AddChannel:
+		if service.Name != "Printer" {
+			t.Fatalf("AddChannel delivered %+v", service)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no service found")
+	}
+
+	// The signal's path is not registered with the other server
+	select {
+	case service := <-ob.AddChannel:
+		t.Fatalf("signal of another object was dispatched: %+v", service)
+	case <-time.After(200 * time.Millisecond):
+	}
+}
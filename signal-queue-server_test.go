@@ -0,0 +1,120 @@
+package avahi_test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/holoplot/go-avahi"
+	"github.com/holoplot/go-avahi/avahitest"
+)
+
+var queueService = avahi.Service{
+	Interface: 2,
+	Protocol:  avahi.ProtoInet,
+	Name:      "Printer",
+	Type:      "_ipp._tcp",
+	Domain:    "local",
+}
+
+func TestSlowConsumer(t *testing.T) {
+	d, s := avahitest.NewServer(t)
+
+	s.SetQueuePolicy(1, avahi.QueueOverflowDropNewest)
+
+	// Nobody reads from this browser's channels
+	slow, err := s.ServiceBrowserNew(avahi.InterfaceUnspec, avahi.ProtoUnspec, "_ipp._tcp", "local", 0)
+	if err != nil {
+		t.Fatalf("ServiceBrowserNew() failed: %v", err)
+	}
+
+	s.SetQueuePolicy(avahi.DefaultQueueSize, avahi.QueueOverflowUnbounded)
+
+	for i := 0; i < 10; i++ {
+		service := queueService
+		service.Name = fmt.Sprintf("Printer %d", i)
+		d.AddService(service)
+	}
+
+	b, err := s.ServiceBrowserNew(avahi.InterfaceUnspec, avahi.ProtoUnspec, "_ipp._tcp", "local", 0)
+	if err != nil {
+		t.Fatalf("ServiceBrowserNew() failed: %v", err)
+	}
+
+	for i := 0; i < 10; i++ {
+		select {
+		case <-b.AddChannel:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("received only %d services", i)
+		}
+	}
+
+	if slow.Dropped() == 0 {
+		t.Fatal("no signals dropped for the slow consumer")
+	}
+}
+
+func TestDefaultQueuePolicy(t *testing.T) {
+	d, s := avahitest.NewServer(t)
+
+	// Nobody reads from this browser's channels
+	_, err := s.ServiceBrowserNew(avahi.InterfaceUnspec, avahi.ProtoUnspec, "_ipp._tcp", "local", 0)
+	if err != nil {
+		t.Fatalf("ServiceBrowserNew() failed: %v", err)
+	}
+
+	b, err := s.ServiceBrowserNew(avahi.InterfaceUnspec, avahi.ProtoUnspec, "_ipp._tcp", "local", 0)
+	if err != nil {
+		t.Fatalf("ServiceBrowserNew() failed: %v", err)
+	}
+
+	count := 2 * avahi.DefaultQueueSize
+
+	go func() {
+		for i := 0; i < count; i++ {
+			service := queueService
+			service.Name = fmt.Sprintf("Printer %d", i)
+			d.AddService(service)
+		}
+	}()
+
+	for i := 0; i < count; i++ {
+		select {
+		case <-b.AddChannel:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("received only %d services", i)
+		}
+	}
+}
+
+func TestCloseSlowConsumer(t *testing.T) {
+	d, s := avahitest.NewServer(t)
+
+	s.SetQueuePolicy(1, avahi.QueueOverflowBlock)
+
+	// Nobody reads from this browser's channels, so its queue fills up and blocks signal delivery
+	_, err := s.ServiceBrowserNew(avahi.InterfaceUnspec, avahi.ProtoUnspec, "_ipp._tcp", "local", 0)
+	if err != nil {
+		t.Fatalf("ServiceBrowserNew() failed: %v", err)
+	}
+
+	for i := 0; i < 10; i++ {
+		service := queueService
+		service.Name = fmt.Sprintf("Printer %d", i)
+		d.AddService(service)
+	}
+
+	time.Sleep(100 * time.Millisecond)
+
+	done := make(chan struct{})
+	go func() {
+		s.Close()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Close() hangs while a queue is full")
+	}
+}
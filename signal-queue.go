@@ -0,0 +1,135 @@
+package avahi
+
+import (
+	"sync"
+
+	dbus "github.com/godbus/dbus/v5"
+)
+
+const (
+	// QueueOverflowBlock - Wait for the consumer when the queue is full. This stalls signal delivery to all other objects of the Server until there is room again.
+	QueueOverflowBlock = 0
+	// QueueOverflowDropOldest - Discard the oldest queued signal to make room for a new one
+	QueueOverflowDropOldest = 1
+	// QueueOverflowDropNewest - Discard new signals while the queue is full
+	QueueOverflowDropNewest = 2
+	// QueueOverflowUnbounded - Never discard signals and let the queue grow without limit
+	QueueOverflowUnbounded = 3
+)
+
+// DefaultQueueSize is a reasonable size for queues bounded with SetQueuePolicy or WithBuffer.
+// Queues are unbounded by default.
+const DefaultQueueSize = 256
+
+// A signalQueue holds the signals for one signal emitter until its own
+// goroutine has delivered them, so a slow consumer of one object's channels
+// does not delay signals for any other object.
+type signalQueue struct {
+	mutex    sync.Mutex
+	cond     *sync.Cond
+	signals  []*dbus.Signal
+	size     int
+	overflow int32
+	dropped  uint64
+	closed   bool
+}
+
+func newSignalQueue() *signalQueue {
+	q := &signalQueue{size: DefaultQueueSize, overflow: QueueOverflowUnbounded}
+	q.cond = sync.NewCond(&q.mutex)
+
+	return q
+}
+
+func (q *signalQueue) setPolicy(size int, overflow int32) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	if size < 1 {
+		size = 1
+	}
+
+	q.size = size
+	q.overflow = overflow
+	q.cond.Broadcast()
+}
+
+// push queues a signal according to the overflow policy. It only blocks
+// for QueueOverflowBlock.
+func (q *signalQueue) push(signal *dbus.Signal) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	for !q.closed && q.overflow == QueueOverflowBlock && len(q.signals) >= q.size {
+		q.cond.Wait()
+	}
+
+	if q.closed {
+		return
+	}
+
+	if q.overflow != QueueOverflowUnbounded && len(q.signals) >= q.size {
+		q.dropped++
+
+		if q.overflow == QueueOverflowDropNewest {
+			return
+		}
+
+		q.signals[0] = nil
+		q.signals = q.signals[1:]
+	}
+
+	q.signals = append(q.signals, signal)
+	q.cond.Broadcast()
+}
+
+// pop returns the oldest queued signal, waiting for one if necessary.
+// It returns false once the queue is closed.
+func (q *signalQueue) pop() (*dbus.Signal, bool) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	for !q.closed && len(q.signals) == 0 {
+		q.cond.Wait()
+	}
+
+	if q.closed {
+		return nil, false
+	}
+
+	signal := q.signals[0]
+	q.signals[0] = nil
+	q.signals = q.signals[1:]
+	q.cond.Broadcast()
+
+	return signal, true
+}
+
+// close discards all queued signals and stops run
+func (q *signalQueue) close() {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	q.closed = true
+	q.signals = nil
+	q.cond.Broadcast()
+}
+
+func (q *signalQueue) droppedCount() uint64 {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	return q.dropped
+}
+
+// run delivers queued signals until the queue is closed
+func (q *signalQueue) run(dispatch func(signal *dbus.Signal) error) {
+	for {
+		signal, ok := q.pop()
+		if !ok {
+			return
+		}
+
+		_ = dispatch(signal)
+	}
+}
@@ -0,0 +1,86 @@
+package avahi
+
+import (
+	"testing"
+	"time"
+
+	dbus "github.com/godbus/dbus/v5"
+)
+
+func testSignals(n int) []*dbus.Signal {
+	signals := make([]*dbus.Signal, n)
+	for i := range signals {
+		signals[i] = &dbus.Signal{Body: []interface{}{i}}
+	}
+
+	return signals
+}
+
+func TestSignalQueueOverflow(t *testing.T) {
+	for _, tc := range []struct {
+		overflow int32
+		first    int
+		length   int
+		dropped  uint64
+	}{
+		{QueueOverflowDropOldest, 2, 3, 2},
+		{QueueOverflowDropNewest, 0, 3, 2},
+		{QueueOverflowUnbounded, 0, 5, 0},
+	} {
+		q := newSignalQueue()
+		q.setPolicy(3, tc.overflow)
+
+		for _, signal := range testSignals(5) {
+			q.push(signal)
+		}
+
+		if len(q.signals) != tc.length || q.droppedCount() != tc.dropped {
+			t.Errorf("overflow %d: queued %d signals and dropped %d", tc.overflow, len(q.signals), q.droppedCount())
+		}
+
+		signal, ok := q.pop()
+		if !ok || signal.Body[0] != tc.first {
+			t.Errorf("overflow %d: first signal is %v", tc.overflow, signal.Body)
+		}
+	}
+}
+
+func TestSignalQueueBlock(t *testing.T) {
+	q := newSignalQueue()
+	q.setPolicy(1, QueueOverflowBlock)
+
+	signals := testSignals(2)
+	q.push(signals[0])
+
+	pushed := make(chan struct{})
+	go func() {
+		q.push(signals[1])
+		close(pushed)
+	}()
+
+	select {
+	case <-pushed:
+		t.Fatal("push() into a full queue did not block")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	for _, expected := range signals {
+		signal, ok := q.pop()
+		if !ok || signal != expected {
+			t.Fatalf("pop() returned %v, %v", signal, ok)
+		}
+	}
+
+	<-pushed
+
+	q.close()
+
+	if _, ok := q.pop(); ok {
+		t.Fatal("pop() from a closed queue succeeded")
+	}
+
+	q.push(signals[0])
+	if q.droppedCount() != 0 {
+		t.Fatal("push() into a closed queue counted as dropped")
+	}
+}
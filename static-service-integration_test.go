@@ -0,0 +1,40 @@
+package avahi_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/holoplot/go-avahi"
+	"github.com/holoplot/go-avahi/avahitest"
+)
+
+func TestStaticServiceGroup(t *testing.T) {
+	d, s := avahitest.NewServer(t)
+
+	g, err := avahi.ParseStaticServiceGroup(strings.NewReader(`<service-group>
+  <name replace-wildcards="yes">Web on %h</name>
+  <service>
+    <type>_http._tcp</type>
+    <port>80</port>
+    <txt-record>path=/</txt-record>
+  </service>
+</service-group>`))
+	if err != nil {
+		t.Fatalf("ParseStaticServiceGroup() failed: %v", err)
+	}
+
+	eg, err := s.EntryGroupNew()
+	if err != nil {
+		t.Fatalf("EntryGroupNew() failed: %v", err)
+	}
+
+	err = eg.ApplyStaticServiceGroup(g)
+	if err != nil {
+		t.Fatalf("ApplyStaticServiceGroup() failed: %v", err)
+	}
+
+	services := d.Services()
+	if len(services) != 1 || services[0].Name != "Web on fakehost" || string(services[0].Txt[0]) != "path=/" {
+		t.Fatalf("published services are %+v", services)
+	}
+}
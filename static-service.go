@@ -0,0 +1,298 @@
+package avahi
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+
+	dbus "github.com/godbus/dbus/v5"
+)
+
+const (
+	// StaticTxtText - The value of a TXT record string is given as text
+	StaticTxtText = "text"
+	// StaticTxtBinaryHex - The value of a TXT record string is hex encoded
+	StaticTxtBinaryHex = "binary-hex"
+	// StaticTxtBinaryBase64 - The value of a TXT record string is base64 encoded
+	StaticTxtBinaryBase64 = "binary-base64"
+)
+
+// staticServiceHeader precedes the XML of service files, as in the files shipped with Avahi
+const staticServiceHeader = "<?xml version=\"1.0\" standalone='no'?>\n" +
+	"<!DOCTYPE service-group SYSTEM \"avahi-service.dtd\">\n"
+
+// A StaticServiceGroup is the content of a static service file as loaded by
+// avahi-daemon from /etc/avahi/services, see avahi.service(5)
+type StaticServiceGroup struct {
+	XMLName  xml.Name          `xml:"service-group"`
+	Name     StaticServiceName `xml:"name"`
+	Services []StaticService   `xml:"service"`
+}
+
+// A StaticServiceName is the name of all services of a StaticServiceGroup.
+// If ReplaceWildcards is set, "%h" is replaced by the host name.
+type StaticServiceName struct {
+	Value            string
+	ReplaceWildcards bool
+}
+
+// A StaticService is a single service of a StaticServiceGroup
+type StaticService struct {
+	// Protocol is "ipv4", "ipv6" or "any". Empty means "any".
+	Protocol string `xml:"protocol,attr,omitempty"`
+	Type     string `xml:"type"`
+	// Subtypes are either complete, like "_color._sub._ipp._tcp", or just the subtype label, like "_color".
+	// Marshal writes complete names, which avahi-daemon expects.
+	Subtypes   []string          `xml:"subtype"`
+	DomainName string            `xml:"domain-name,omitempty"`
+	HostName   string            `xml:"host-name,omitempty"`
+	Port       uint16            `xml:"port"`
+	TxtRecords []StaticTxtRecord `xml:"txt-record"`
+}
+
+// A StaticTxtRecord is a single string of the TXT record of a StaticService.
+// With a binary ValueFormat, only the part after the first '=' is encoded.
+type StaticTxtRecord struct {
+	ValueFormat string `xml:"value-format,attr,omitempty"`
+	Value       string `xml:",chardata"`
+}
+
+type staticServiceNameXML struct {
+	ReplaceWildcards string `xml:"replace-wildcards,attr,omitempty"`
+	Value            string `xml:",chardata"`
+}
+
+// MarshalXML writes replace-wildcards as "yes" or leaves it out
+func (n StaticServiceName) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	x := staticServiceNameXML{Value: n.Value}
+	if n.ReplaceWildcards {
+		x.ReplaceWildcards = "yes"
+	}
+
+	return e.EncodeElement(x, start)
+}
+
+// UnmarshalXML reads replace-wildcards, which is "yes" or "no"
+func (n *StaticServiceName) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	var x staticServiceNameXML
+
+	err := d.DecodeElement(&x, &start)
+	if err != nil {
+		return err
+	}
+
+	switch x.ReplaceWildcards {
+	case "", "no":
+	case "yes":
+		n.ReplaceWildcards = true
+	default:
+		return fmt.Errorf("invalid replace-wildcards value %q", x.ReplaceWildcards)
+	}
+
+	n.Value = x.Value
+
+	return nil
+}
+
+// ParseStaticServiceGroup reads a static service file. The result is not validated.
+func ParseStaticServiceGroup(r io.Reader) (*StaticServiceGroup, error) {
+	g := new(StaticServiceGroup)
+
+	err := xml.NewDecoder(r).Decode(g)
+	if err != nil {
+		return nil, err
+	}
+
+	return g, nil
+}
+
+// Marshal returns the group in the format of static service files
+func (g StaticServiceGroup) Marshal() ([]byte, error) {
+	services := make([]StaticService, len(g.Services))
+
+	for i, s := range g.Services {
+		services[i] = s
+		services[i].Subtypes = nil
+
+		for _, st := range s.Subtypes {
+			services[i].Subtypes = append(services[i].Subtypes, ServiceSpec{Type: s.Type}.subtype(st))
+		}
+	}
+
+	g.Services = services
+
+	data, err := xml.MarshalIndent(g, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	return append(append([]byte(staticServiceHeader), data...), '\n'), nil
+}
+
+// Bytes returns the decoded TXT record string
+func (t StaticTxtRecord) Bytes() ([]byte, error) {
+	if t.ValueFormat == "" || t.ValueFormat == StaticTxtText {
+		return []byte(t.Value), nil
+	}
+
+	key, value, found := strings.Cut(t.Value, "=")
+	if !found {
+		return []byte(key), nil
+	}
+
+	var decoded []byte
+	var err error
+
+	switch t.ValueFormat {
+	case StaticTxtBinaryHex:
+		decoded, err = hex.DecodeString(value)
+	case StaticTxtBinaryBase64:
+		decoded, err = base64.StdEncoding.DecodeString(value)
+	default:
+		return nil, fmt.Errorf("unknown value-format %q", t.ValueFormat)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("value of TXT record string %q: %w", key, err)
+	}
+
+	return append([]byte(key+"="), decoded...), nil
+}
+
+// StaticTxtRecordOf encodes a TXT record string, as text if its value is printable
+// UTF-8 without leading or trailing white space, which would be lost, and as hex otherwise
+func StaticTxtRecordOf(s []byte) StaticTxtRecord {
+	key, value, found := bytes.Cut(s, []byte("="))
+
+	printable := utf8.Valid(value) && len(bytes.TrimSpace(value)) == len(value) &&
+		bytes.IndexFunc(value, func(r rune) bool { return r < 0x20 || r == 0x7f }) < 0
+
+	if !found || printable {
+		return StaticTxtRecord{Value: string(s)}
+	}
+
+	return StaticTxtRecord{ValueFormat: StaticTxtBinaryHex, Value: string(key) + "=" + hex.EncodeToString(value)}
+}
+
+// Txt returns the decoded TXT record strings of the service
+func (s StaticService) Txt() ([][]byte, error) {
+	var txt [][]byte
+
+	for _, t := range s.TxtRecords {
+		b, err := t.Bytes()
+		if err != nil {
+			return nil, err
+		}
+
+		txt = append(txt, b)
+	}
+
+	return txt, nil
+}
+
+func staticProtocol(protocol string) (int32, error) {
+	switch protocol {
+	case "", "any":
+		return ProtoUnspec, nil
+	case "ipv4":
+		return ProtoInet, nil
+	case "ipv6":
+		return ProtoInet6, nil
+	}
+
+	return 0, fmt.Errorf("%w: %q", ErrInvalidProtocol, protocol)
+}
+
+// Validate checks the group for errors avahi-daemon would report when loading it
+func (g StaticServiceGroup) Validate() error {
+	if len(g.Services) == 0 {
+		return fmt.Errorf("%w: service group %q has no services", ErrIsEmpty, g.Name.Value)
+	}
+
+	spec, err := g.Spec("localhost")
+	if err != nil {
+		return err
+	}
+
+	return spec.Validate()
+}
+
+// Spec converts the group to an EntryGroupSpec. If the name replaces wildcards,
+// "%h" is replaced by hostName.
+func (g StaticServiceGroup) Spec(hostName string) (EntryGroupSpec, error) {
+	var spec EntryGroupSpec
+
+	name := g.Name.Value
+	if g.Name.ReplaceWildcards {
+		name = strings.ReplaceAll(name, "%h", hostName)
+	}
+
+	for _, s := range g.Services {
+		protocol, err := staticProtocol(s.Protocol)
+		if err != nil {
+			return spec, err
+		}
+
+		txt, err := s.Txt()
+		if err != nil {
+			return spec, err
+		}
+
+		spec.Services = append(spec.Services, ServiceSpec{
+			Interface: InterfaceUnspec,
+			Protocol:  protocol,
+			Name:      name,
+			Type:      s.Type,
+			Domain:    s.DomainName,
+			Host:      s.HostName,
+			Port:      s.Port,
+			Txt:       txt,
+			Subtypes:  s.Subtypes,
+		})
+	}
+
+	return spec, nil
+}
+
+// ApplyStaticServiceGroup validates g and publishes its services with ApplySpec.
+// Wildcards in the name are replaced by the host name of the daemon.
+func (c *EntryGroup) ApplyStaticServiceGroup(g *StaticServiceGroup) error {
+	err := g.Validate()
+	if err != nil {
+		return err
+	}
+
+	var hostName string
+
+	if g.Name.ReplaceWildcards {
+		hostName, err = serverHostName(c.conn)
+		if err != nil {
+			return err
+		}
+	}
+
+	spec, err := g.Spec(hostName)
+	if err != nil {
+		return err
+	}
+
+	return c.ApplySpec(spec)
+}
+
+// serverHostName returns the host name of the daemon for objects that have no Server at hand
+func serverHostName(conn *dbus.Conn) (string, error) {
+	var name string
+
+	err := conn.Object("org.freedesktop.Avahi", "/").
+		Call("org.freedesktop.Avahi.Server.GetHostName", 0).Store(&name)
+	if err != nil {
+		return "", avahiError(err)
+	}
+
+	return name, nil
+}
@@ -0,0 +1,142 @@
+package avahi
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const testStaticService = `<?xml version="1.0" standalone='no'?><!--*-nxml-*-->
+<!DOCTYPE service-group SYSTEM "avahi-service.dtd">
+<service-group>
+  <name replace-wildcards="yes">Printer on %h</name>
+  <service protocol="ipv4">
+    <type>_ipp._tcp</type>
+    <subtype>_color._sub._ipp._tcp</subtype>
+    <port>631</port>
+    <txt-record>txtvers=1</txt-record>
+    <txt-record value-format="binary-hex">key=00ff</txt-record>
+  </service>
+  <service>
+    <type>_http._tcp</type>
+    <domain-name>example.com</domain-name>
+    <host-name>printer.example.com</host-name>
+    <port>80</port>
+  </service>
+</service-group>
+`
+
+func TestParseStaticServiceGroup(t *testing.T) {
+	g, err := ParseStaticServiceGroup(strings.NewReader(testStaticService))
+	if err != nil {
+		t.Fatalf("ParseStaticServiceGroup() failed: %v", err)
+	}
+
+	if err := g.Validate(); err != nil {
+		t.Fatalf("Validate() failed: %v", err)
+	}
+
+	spec, err := g.Spec("office")
+	if err != nil {
+		t.Fatalf("Spec() failed: %v", err)
+	}
+
+	expected := []ServiceSpec{
+		{
+			Interface: InterfaceUnspec,
+			Protocol:  ProtoInet,
+			Name:      "Printer on office",
+			Type:      "_ipp._tcp",
+			Port:      631,
+			Txt:       [][]byte{[]byte("txtvers=1"), []byte("key=\x00\xff")},
+			Subtypes:  []string{"_color._sub._ipp._tcp"},
+		},
+		{
+			Interface: InterfaceUnspec,
+			Protocol:  ProtoUnspec,
+			Name:      "Printer on office",
+			Type:      "_http._tcp",
+			Domain:    "example.com",
+			Host:      "printer.example.com",
+			Port:      80,
+		},
+	}
+
+	if !reflect.DeepEqual(spec.Services, expected) {
+		t.Fatalf("Spec() returned %+v", spec.Services)
+	}
+
+	data, err := g.Marshal()
+	if err != nil {
+		t.Fatalf("Marshal() failed: %v", err)
+	}
+
+	if !bytes.HasPrefix(data, []byte(staticServiceHeader+"<service-group>")) {
+		t.Fatalf("Marshal() returned\n%s", data)
+	}
+
+	parsed, err := ParseStaticServiceGroup(bytes.NewReader(data))
+	if err != nil || !reflect.DeepEqual(parsed, g) {
+		t.Fatalf("parsing the marshaled group returned %+v, %v", parsed, err)
+	}
+}
+
+func TestStaticServiceGroupValidate(t *testing.T) {
+	valid := StaticServiceGroup{
+		Name:     StaticServiceName{Value: "Web"},
+		Services: []StaticService{{Type: "_http._tcp", Port: 80}},
+	}
+
+	tests := []struct {
+		modify func(g *StaticServiceGroup)
+		err    error
+	}{
+		{func(g *StaticServiceGroup) { g.Services = nil }, ErrIsEmpty},
+		{func(g *StaticServiceGroup) { g.Name.Value = "" }, ErrInvalidServiceName},
+		{func(g *StaticServiceGroup) { g.Services[0].Type = "http" }, ErrInvalidServiceType},
+		{func(g *StaticServiceGroup) { g.Services[0].Protocol = "ipx" }, ErrInvalidProtocol},
+		{func(g *StaticServiceGroup) { g.Services[0].Subtypes = []string{"_printer._sub._http._tcp"} }, nil},
+		{func(g *StaticServiceGroup) { g.Services[0].Subtypes = []string{"_printer._sub._HTTP._tcp"} }, nil},
+		{func(g *StaticServiceGroup) { g.Services[0].Subtypes = []string{"_printer"} }, nil},
+		{func(g *StaticServiceGroup) { g.Services[0].Subtypes = []string{"_printer._sub._ipp._tcp"} }, ErrInvalidServiceSubtype},
+		{func(g *StaticServiceGroup) { g.Services[0].Subtypes = []string{"_printer._sub._http._tcp.x"} }, ErrInvalidServiceSubtype},
+	}
+
+	for i, test := range tests {
+		g := valid
+		g.Services = append([]StaticService(nil), valid.Services...)
+		test.modify(&g)
+
+		if err := g.Validate(); !errors.Is(err, test.err) {
+			t.Errorf("test %d: Validate() returned %v, expected %v", i, err, test.err)
+		}
+	}
+
+	g := valid
+	g.Services = []StaticService{{Type: "_http._tcp", Subtypes: []string{"_printer"}}}
+	if data, err := g.Marshal(); err != nil || !bytes.Contains(data, []byte("<subtype>_printer._sub._http._tcp</subtype>")) {
+		t.Errorf("Marshal() returned\n%s", data)
+	}
+
+	g.Services = []StaticService{{Type: "_http._tcp", TxtRecords: []StaticTxtRecord{{ValueFormat: StaticTxtBinaryHex, Value: "key=xyz"}}}}
+	if err := g.Validate(); err == nil {
+		t.Error("Validate() accepted invalid hex")
+	}
+}
+
+func TestStaticTxtRecordOf(t *testing.T) {
+	for _, s := range [][]byte{[]byte("path=/"), []byte("flag"), []byte("key=\x00\xff"), []byte("key= padded ")} {
+		r := StaticTxtRecordOf(s)
+
+		b, err := r.Bytes()
+		if err != nil || !bytes.Equal(b, s) {
+			t.Errorf("StaticTxtRecordOf(%q) returned %+v, which decodes to %q, %v", s, r, b, err)
+		}
+	}
+
+	if r := StaticTxtRecordOf([]byte("key=\x00")); r.ValueFormat != StaticTxtBinaryHex || r.Value != "key=00" {
+		t.Errorf("StaticTxtRecordOf() returned %+v", r)
+	}
+}
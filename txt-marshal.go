@@ -0,0 +1,191 @@
+package avahi
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+type txtField struct {
+	index     int
+	key       string
+	omitEmpty bool
+}
+
+// txtFields returns the fields of struct type t carrying a txt tag.
+// A tag has the form `txt:"key"` or `txt:"key,omitempty"`, fields tagged `txt:"-"` are skipped.
+func txtFields(t reflect.Type) []txtField {
+	var fields []txtField
+
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+
+		tag, ok := f.Tag.Lookup("txt")
+		if !ok || tag == "-" || f.PkgPath != "" {
+			continue
+		}
+
+		parts := strings.Split(tag, ",")
+		field := txtField{index: i, key: parts[0]}
+
+		for _, option := range parts[1:] {
+			if option == "omitempty" {
+				field.omitEmpty = true
+			}
+		}
+
+		fields = append(fields, field)
+	}
+
+	return fields
+}
+
+func txtStruct(v interface{}, function string) (reflect.Value, error) {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() == reflect.Ptr && !rv.IsNil() {
+		rv = rv.Elem()
+	}
+
+	if rv.Kind() != reflect.Struct {
+		return reflect.Value{}, fmt.Errorf("%s: expected struct or pointer to struct, got %T", function, v)
+	}
+
+	return rv, nil
+}
+
+// MarshalTxt encodes the tagged fields of the struct v into TXT record strings,
+// suitable for EntryGroup.AddService and EntryGroup.UpdateServiceTxt.
+//
+// Fields are tagged `txt:"key"` and may be strings, signed or unsigned integers, bools,
+// []byte or time.Duration. A true bool is encoded as a boolean attribute without value,
+// a false one is left out. With `txt:"key,omitempty"`, fields with a zero value are left out.
+func MarshalTxt(v interface{}) ([][]byte, error) {
+	t, err := MarshalTxtRecord(v)
+	if err != nil {
+		return nil, err
+	}
+
+	return t.Encode()
+}
+
+// MarshalTxtRecord is like MarshalTxt, but returns the unencoded Txt
+func MarshalTxtRecord(v interface{}) (Txt, error) {
+	rv, err := txtStruct(v, "MarshalTxt")
+	if err != nil {
+		return nil, err
+	}
+
+	var t Txt
+
+	for _, field := range txtFields(rv.Type()) {
+		fv := rv.Field(field.index)
+
+		if field.omitEmpty && fv.IsZero() {
+			continue
+		}
+
+		switch {
+		case fv.Type() == durationType:
+			t.SetString(field.key, time.Duration(fv.Int()).String())
+
+		case fv.Kind() == reflect.String:
+			t.SetString(field.key, fv.String())
+
+		case fv.Kind() == reflect.Bool:
+			t.SetBool(field.key, fv.Bool())
+
+		case fv.Kind() >= reflect.Int && fv.Kind() <= reflect.Int64:
+			t.SetString(field.key, strconv.FormatInt(fv.Int(), 10))
+
+		case fv.Kind() >= reflect.Uint && fv.Kind() <= reflect.Uint64:
+			t.SetString(field.key, strconv.FormatUint(fv.Uint(), 10))
+
+		case fv.Kind() == reflect.Slice && fv.Type().Elem().Kind() == reflect.Uint8:
+			t.Set(field.key, fv.Bytes())
+
+		default:
+			return nil, fmt.Errorf("MarshalTxt: unsupported type %s for key %q", fv.Type(), field.key)
+		}
+	}
+
+	return t, nil
+}
+
+// UnmarshalTxt decodes TXT record strings, e.g. Service.Txt, into the tagged fields of
+// the struct pointed to by v. See MarshalTxt for the supported tags and types.
+// Keys are matched case-insensitively and fields whose key is absent are left untouched.
+// A bool field is set to true for a boolean attribute without value.
+func UnmarshalTxt(txt [][]byte, v interface{}) error {
+	return UnmarshalTxtRecord(ParseTxt(txt), v)
+}
+
+// UnmarshalTxtRecord is like UnmarshalTxt, but decodes an already parsed Txt
+func UnmarshalTxtRecord(t Txt, v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return fmt.Errorf("UnmarshalTxt: expected non-nil pointer to struct, got %T", v)
+	}
+
+	rv, err := txtStruct(v, "UnmarshalTxt")
+	if err != nil {
+		return err
+	}
+
+	for _, field := range txtFields(rv.Type()) {
+		value, ok := t.Get(field.key)
+		if !ok {
+			continue
+		}
+
+		fv := rv.Field(field.index)
+		s := string(value)
+
+		switch {
+		case fv.Type() == durationType:
+			d, err := time.ParseDuration(s)
+			if err != nil {
+				return fmt.Errorf("UnmarshalTxt: key %q: %w", field.key, err)
+			}
+			fv.SetInt(int64(d))
+
+		case fv.Kind() == reflect.String:
+			fv.SetString(s)
+
+		case fv.Kind() == reflect.Bool:
+			b := true
+			if len(value) > 0 {
+				b, err = strconv.ParseBool(s)
+				if err != nil {
+					return fmt.Errorf("UnmarshalTxt: key %q: %w", field.key, err)
+				}
+			}
+			fv.SetBool(b)
+
+		case fv.Kind() >= reflect.Int && fv.Kind() <= reflect.Int64:
+			i, err := strconv.ParseInt(s, 10, fv.Type().Bits())
+			if err != nil {
+				return fmt.Errorf("UnmarshalTxt: key %q: %w", field.key, err)
+			}
+			fv.SetInt(i)
+
+		case fv.Kind() >= reflect.Uint && fv.Kind() <= reflect.Uint64:
+			u, err := strconv.ParseUint(s, 10, fv.Type().Bits())
+			if err != nil {
+				return fmt.Errorf("UnmarshalTxt: key %q: %w", field.key, err)
+			}
+			fv.SetUint(u)
+
+		case fv.Kind() == reflect.Slice && fv.Type().Elem().Kind() == reflect.Uint8:
+			fv.SetBytes(append([]byte{}, value...))
+
+		default:
+			return fmt.Errorf("UnmarshalTxt: unsupported type %s for key %q", fv.Type(), field.key)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,73 @@
+package avahi
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+type txtTestDevice struct {
+	Version  int           `txt:"txtvers"`
+	Model    string        `txt:"model"`
+	Secure   bool          `txt:"secure"`
+	Channels uint8         `txt:"ch,omitempty"`
+	Interval time.Duration `txt:"interval"`
+	Key      []byte        `txt:"key,omitempty"`
+	Ignored  string        `txt:"-"`
+	Untagged string
+}
+
+func TestMarshalTxt(t *testing.T) {
+	txt, err := MarshalTxt(txtTestDevice{
+		Version:  1,
+		Model:    "X-100",
+		Secure:   true,
+		Interval: 90 * time.Second,
+		Ignored:  "ignored",
+		Untagged: "untagged",
+	})
+	if err != nil {
+		t.Fatalf("MarshalTxt() failed: %v", err)
+	}
+
+	expected := [][]byte{[]byte("txtvers=1"), []byte("model=X-100"), []byte("secure"), []byte("interval=1m30s")}
+	if len(txt) != len(expected) {
+		t.Fatalf("MarshalTxt() returned %q, expected %q", txt, expected)
+	}
+	for i := range expected {
+		if !bytes.Equal(txt[i], expected[i]) {
+			t.Fatalf("MarshalTxt() returned %q, expected %q", txt, expected)
+		}
+	}
+}
+
+func TestUnmarshalTxt(t *testing.T) {
+	var d txtTestDevice
+
+	err := UnmarshalTxt([][]byte{
+		[]byte("TXTVERS=2"),
+		[]byte("model=Y-200"),
+		[]byte("secure"),
+		[]byte("ch=16"),
+		[]byte("interval=5s"),
+		[]byte("key=\x00\x01"),
+	}, &d)
+	if err != nil {
+		t.Fatalf("UnmarshalTxt() failed: %v", err)
+	}
+
+	if d.Version != 2 || d.Model != "Y-200" || !d.Secure || d.Channels != 16 ||
+		d.Interval != 5*time.Second || !bytes.Equal(d.Key, []byte{0, 1}) {
+		t.Fatalf("UnmarshalTxt() returned %+v", d)
+	}
+
+	err = UnmarshalTxt([][]byte{[]byte("ch=256")}, &d)
+	if err == nil {
+		t.Fatal("UnmarshalTxt() accepted an out of range value")
+	}
+
+	err = UnmarshalTxt(nil, d)
+	if err == nil {
+		t.Fatal("UnmarshalTxt() accepted a non-pointer")
+	}
+}
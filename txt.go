@@ -0,0 +1,226 @@
+package avahi
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const (
+	// TxtMaxStringSize - Maximum size of a single key/value string in a TXT record
+	TxtMaxStringSize = 255
+	// TxtRecommendedSize - Recommended maximum total size of a TXT record, see RFC 6763 section 6.2
+	TxtRecommendedSize = 400
+	// TxtMaxRecommendedSize - Total TXT record size above which RFC 6763 advises against publishing
+	TxtMaxRecommendedSize = 1300
+)
+
+var (
+	// ErrTxtInvalidKey is returned for empty keys and keys with characters outside printable US-ASCII or containing '='
+	ErrTxtInvalidKey = errors.New("invalid TXT record key")
+	// ErrTxtDuplicateKey is returned when a key occurs more than once
+	ErrTxtDuplicateKey = errors.New("duplicate TXT record key")
+	// ErrTxtStringTooLong is returned when a key/value string exceeds TxtMaxStringSize
+	ErrTxtStringTooLong = errors.New("TXT record string too long")
+	// ErrTxtTooLarge is returned by Validate when the record exceeds TxtMaxRecommendedSize
+	ErrTxtTooLarge = errors.New("TXT record too large")
+	// ErrTxtAboveRecommendedSize is returned by Validate when the record exceeds TxtRecommendedSize,
+	// but not TxtMaxRecommendedSize. Such a record can still be published, so callers may treat this as a warning.
+	ErrTxtAboveRecommendedSize = errors.New("TXT record above recommended size")
+)
+
+// A TxtEntry is a single attribute of a TXT record.
+// A nil Value denotes a boolean attribute that is present without a value ("key"),
+// an empty Value one that has an empty value ("key=").
+type TxtEntry struct {
+	Key   string
+	Value []byte
+}
+
+// A Txt is an ordered list of DNS-SD TXT record attributes as described in RFC 6763 section 6.
+// Keys are compared case-insensitively.
+type Txt []TxtEntry
+
+// ParseTxt parses the strings of a TXT record, e.g. Service.Txt. As mandated by RFC 6763,
+// strings with an empty key are ignored and only the first occurrence of a key is used.
+func ParseTxt(txt [][]byte) Txt {
+	var t Txt
+
+	for _, s := range txt {
+		var e TxtEntry
+
+		if i := bytes.IndexByte(s, '='); i >= 0 {
+			e.Key = string(s[:i])
+			e.Value = append([]byte{}, s[i+1:]...)
+		} else {
+			e.Key = string(s)
+		}
+
+		if e.Key == "" || t.Has(e.Key) {
+			continue
+		}
+
+		t = append(t, e)
+	}
+
+	return t
+}
+
+// TxtRecord returns the parsed TXT record of a resolved service
+func (s Service) TxtRecord() Txt {
+	return ParseTxt(s.Txt)
+}
+
+func (t Txt) index(key string) int {
+	for i, e := range t {
+		if strings.EqualFold(e.Key, key) {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// Has reports whether key is present, with or without a value
+func (t Txt) Has(key string) bool {
+	return t.index(key) >= 0
+}
+
+// Get returns the value of key. The value is nil for boolean attributes.
+func (t Txt) Get(key string) ([]byte, bool) {
+	i := t.index(key)
+	if i < 0 {
+		return nil, false
+	}
+
+	return t[i].Value, true
+}
+
+// GetString returns the value of key as a string
+func (t Txt) GetString(key string) (string, bool) {
+	v, ok := t.Get(key)
+	return string(v), ok
+}
+
+// Set sets the value of key, replacing an existing value or appending a new attribute
+func (t *Txt) Set(key string, value []byte) {
+	if value == nil {
+		value = []byte{}
+	}
+
+	t.set(key, value)
+}
+
+// SetString sets the value of key to a string
+func (t *Txt) SetString(key, value string) {
+	t.set(key, []byte(value))
+}
+
+// SetBool adds key as a boolean attribute without a value if b is true, and removes it otherwise
+func (t *Txt) SetBool(key string, b bool) {
+	if b {
+		t.set(key, nil)
+	} else {
+		t.Delete(key)
+	}
+}
+
+func (t *Txt) set(key string, value []byte) {
+	if i := t.index(key); i >= 0 {
+		(*t)[i].Value = value
+		return
+	}
+
+	*t = append(*t, TxtEntry{Key: key, Value: value})
+}
+
+// Delete removes key
+func (t *Txt) Delete(key string) {
+	if i := t.index(key); i >= 0 {
+		*t = append((*t)[:i], (*t)[i+1:]...)
+	}
+}
+
+func (e TxtEntry) encode() []byte {
+	if e.Value == nil {
+		return []byte(e.Key)
+	}
+
+	b := make([]byte, 0, len(e.Key)+1+len(e.Value))
+	b = append(b, e.Key...)
+	b = append(b, '=')
+	b = append(b, e.Value...)
+
+	return b
+}
+
+func validTxtKey(key string) bool {
+	if key == "" {
+		return false
+	}
+
+	for i := 0; i < len(key); i++ {
+		if key[i] < 0x20 || key[i] > 0x7e || key[i] == '=' {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Size returns the size of the record on the wire, including the length byte of every string
+func (t Txt) Size() int {
+	size := 0
+	for _, e := range t {
+		size += 1 + len(e.encode())
+	}
+
+	return size
+}
+
+// Encode returns the strings of the TXT record in order, suitable for EntryGroup.AddService
+// and EntryGroup.UpdateServiceTxt. It fails on invalid or duplicate keys and strings
+// exceeding TxtMaxStringSize.
+func (t Txt) Encode() ([][]byte, error) {
+	txt := make([][]byte, 0, len(t))
+
+	for i, e := range t {
+		if !validTxtKey(e.Key) {
+			return nil, fmt.Errorf("%w: %q", ErrTxtInvalidKey, e.Key)
+		}
+
+		if t.index(e.Key) != i {
+			return nil, fmt.Errorf("%w: %q", ErrTxtDuplicateKey, e.Key)
+		}
+
+		s := e.encode()
+		if len(s) > TxtMaxStringSize {
+			return nil, fmt.Errorf("%w: %q has %d bytes", ErrTxtStringTooLong, e.Key, len(s))
+		}
+
+		txt = append(txt, s)
+	}
+
+	return txt, nil
+}
+
+// Validate checks that the record can be encoded and does not exceed the sizes of RFC 6763 section 6.2.
+// Above TxtMaxRecommendedSize it returns ErrTxtTooLarge, above TxtRecommendedSize ErrTxtAboveRecommendedSize.
+func (t Txt) Validate() error {
+	_, err := t.Encode()
+	if err != nil {
+		return err
+	}
+
+	size := t.Size()
+
+	switch {
+	case size > TxtMaxRecommendedSize:
+		return fmt.Errorf("%w: %d bytes", ErrTxtTooLarge, size)
+	case size > TxtRecommendedSize:
+		return fmt.Errorf("%w: %d bytes", ErrTxtAboveRecommendedSize, size)
+	}
+
+	return nil
+}
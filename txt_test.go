@@ -0,0 +1,116 @@
+package avahi
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestParseTxt(t *testing.T) {
+	txt := ParseTxt([][]byte{
+		[]byte("path=/index.html"),
+		[]byte("Secure"),
+		[]byte("empty="),
+		[]byte("=ignored"),
+		[]byte("PATH=/duplicate"),
+		[]byte("blob=a=b"),
+	})
+
+	if len(txt) != 4 {
+		t.Fatalf("ParseTxt() returned %d entries, expected 4: %v", len(txt), txt)
+	}
+
+	v, ok := txt.GetString("Path")
+	if !ok || v != "/index.html" {
+		t.Fatalf("Get(Path) returned %q, %v", v, ok)
+	}
+
+	b, ok := txt.Get("secure")
+	if !ok || b != nil {
+		t.Fatalf("Get(secure) returned %v, %v, expected boolean attribute", b, ok)
+	}
+
+	b, ok = txt.Get("empty")
+	if !ok || b == nil || len(b) != 0 {
+		t.Fatalf("Get(empty) returned %v, %v, expected empty value", b, ok)
+	}
+
+	v, _ = txt.GetString("blob")
+	if v != "a=b" {
+		t.Fatalf("Get(blob) returned %q", v)
+	}
+
+	if txt.Has("missing") {
+		t.Fatal("Has(missing) returned true")
+	}
+}
+
+func TestTxtEncode(t *testing.T) {
+	var txt Txt
+
+	txt.SetString("txtvers", "1")
+	txt.SetBool("secure", true)
+	txt.Set("empty", nil)
+	txt.SetString("TXTVERS", "2")
+
+	encoded, err := txt.Encode()
+	if err != nil {
+		t.Fatalf("Encode() failed: %v", err)
+	}
+
+	expected := [][]byte{[]byte("txtvers=2"), []byte("secure"), []byte("empty=")}
+	if len(encoded) != len(expected) {
+		t.Fatalf("Encode() returned %q, expected %q", encoded, expected)
+	}
+	for i := range expected {
+		if !bytes.Equal(encoded[i], expected[i]) {
+			t.Fatalf("Encode() returned %q, expected %q", encoded, expected)
+		}
+	}
+
+	if txt.Size() != 24 {
+		t.Fatalf("Size() returned %d, expected 24", txt.Size())
+	}
+
+	txt.SetBool("secure", false)
+	if txt.Has("secure") {
+		t.Fatal("SetBool(false) did not remove the key")
+	}
+}
+
+func TestTxtValidate(t *testing.T) {
+	for _, tc := range []struct {
+		txt Txt
+		err error
+	}{
+		{Txt{{Key: "a=b"}}, ErrTxtInvalidKey},
+		{Txt{{Key: ""}}, ErrTxtInvalidKey},
+		{Txt{{Key: "k\x7f"}}, ErrTxtInvalidKey},
+		{Txt{{Key: "a"}, {Key: "A"}}, ErrTxtDuplicateKey},
+		{Txt{{Key: "a", Value: []byte(strings.Repeat("x", 254))}}, ErrTxtStringTooLong},
+		{Txt{{Key: "a", Value: []byte(strings.Repeat("x", 253))}}, nil},
+	} {
+		err := tc.txt.Validate()
+		if !errors.Is(err, tc.err) {
+			t.Errorf("Validate(%q) returned %v, expected %v", tc.txt[0].Key, err, tc.err)
+		}
+	}
+
+	var txt Txt
+	for _, k := range []string{"a", "b"} {
+		txt.SetString(k, strings.Repeat("x", 250))
+	}
+
+	if err := txt.Validate(); !errors.Is(err, ErrTxtAboveRecommendedSize) || errors.Is(err, ErrTxtTooLarge) {
+		t.Fatalf("Validate() returned %v, expected %v", err, ErrTxtAboveRecommendedSize)
+	}
+
+	for _, k := range []string{"c", "d", "e", "f"} {
+		txt.SetString(k, strings.Repeat("x", 250))
+	}
+
+	if err := txt.Validate(); !errors.Is(err, ErrTxtTooLarge) {
+		t.Fatalf("Validate() returned %v, expected %v", err, ErrTxtTooLarge)
+	}
+}